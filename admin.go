@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Default & max number of users returned by the user search
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 500
+)
+
+// Lists users, optionally filtered by a search string matched against names & emails
+func (cfg *apiConfig) handlerAdminUsersGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit := defaultUsersLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			respondWithError(w, 400, "Invalid limit")
+			return
+		}
+		limit = min(parsed, maxUsersLimit)
+	}
+
+	users, err := cfg.DB.SearchUsers(r.Context(), database.SearchUsersParams{
+		Query:      strings.TrimSpace(r.URL.Query().Get("q")),
+		LimitCount: int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	payload := []User{}
+	for _, dbUser := range users {
+		payload = append(payload, databaseUserToUser(dbUser))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Disables a user's account & ends their sessions. Their API keys stop working until they're enabled again
+func (cfg *apiConfig) handlerAdminUsersDisablePost(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.setUserDisabled(w, r, user, true)
+}
+
+// Re-enables a disabled account
+func (cfg *apiConfig) handlerAdminUsersEnablePost(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.setUserDisabled(w, r, user, false)
+}
+
+// Shared by the disable & enable endpoints
+func (cfg *apiConfig) setUserDisabled(w http.ResponseWriter, r *http.Request, admin database.User, disabled bool) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid UserID")
+		return
+	}
+	if userID == admin.ID {
+		respondWithError(w, 400, "Cannot change your own account's status")
+		return
+	}
+
+	disabledAt := sql.NullTime{}
+	if disabled {
+		disabledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	dbUser, err := cfg.DB.SetUserDisabledAt(r.Context(), database.SetUserDisabledAtParams{
+		ID:         userID,
+		DisabledAt: disabledAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if disabled {
+		err = cfg.DB.DeleteUserSessions(r.Context(), userID)
+		if err != nil {
+			log.Printf("Error deleting sessions: %s", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+	}
+	log.Printf("Admin %v set disabled=%v on user %v", admin.ID, disabled, userID)
+	respondWithJSON(w, 200, databaseUserToUser(dbUser))
+}
+
+// Deletes a user's account, their feeds are kept for other followers
+func (cfg *apiConfig) handlerAdminUsersDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid UserID")
+		return
+	}
+	if userID == user.ID {
+		respondWithError(w, 400, "Cannot delete your own account from the admin API")
+		return
+	}
+
+	deleted, err := cfg.deleteUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error deleting user: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	log.Printf("Admin %v deleted user %v", user.ID, userID)
+	respondWithJSON(w, 200, "OK")
+}
+
+// Returned when impersonating a user. There's no refresh token, a new session is needed once the access token expires
+type impersonationTokens struct {
+	AccessToken     string    `json:"access_token"`
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+	User            User      `json:"user"`
+	ImpersonatedBy  uuid.UUID `json:"impersonated_by"`
+}
+
+// Starts a short-lived session as another user, for debugging what they see. Admins can't be impersonated.
+// The session records the admin who started it, & every impersonation is logged
+func (cfg *apiConfig) handlerAdminUsersImpersonatePost(w http.ResponseWriter, r *http.Request, user database.User) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid UserID")
+		return
+	}
+	target, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "User not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if target.DisabledAt.Valid {
+		respondWithErrorCode(w, 403, "account_disabled", "Account is disabled")
+		return
+	}
+	if target.IsAdmin {
+		respondWithError(w, 403, "Admins can't be impersonated")
+		return
+	}
+
+	tokens, err := cfg.createImpersonationSession(r.Context(), user, target)
+	if err != nil {
+		log.Printf("Error creating session: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	log.Printf("Admin %v is impersonating user %v", user.ID, target.ID)
+	respondWithJSON(w, 200, tokens)
+}
+
+// Starts an access-only session as target. The refresh token is never handed out & expires with the access token
+func (cfg *apiConfig) createImpersonationSession(ctx context.Context, admin database.User, target database.User) (impersonationTokens, error) {
+	accessToken, err := generateToken()
+	if err != nil {
+		return impersonationTokens{}, err
+	}
+	refreshToken, err := generateToken()
+	if err != nil {
+		return impersonationTokens{}, err
+	}
+	expiresAt := time.Now().Add(accessTokenTTL)
+	_, err = cfg.DB.CreateSession(ctx, database.CreateSessionParams{
+		ID:               uuid.New(),
+		UserID:           target.ID,
+		AccessTokenHash:  hashToken(accessToken),
+		AccessExpiresAt:  expiresAt,
+		RefreshTokenHash: hashToken(refreshToken),
+		RefreshExpiresAt: expiresAt,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		ImpersonatedBy:   uuid.NullUUID{UUID: admin.ID, Valid: true},
+	})
+	if err != nil {
+		return impersonationTokens{}, err
+	}
+	return impersonationTokens{
+		AccessToken:     accessToken,
+		AccessExpiresAt: expiresAt,
+		User:            databaseUserToUser(target),
+		ImpersonatedBy:  admin.ID,
+	}, nil
+}
+
+// Deletes a feed regardless of who follows it, its posts & follows are removed along with it
+func (cfg *apiConfig) handlerAdminFeedsDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FeedID")
+		return
+	}
+
+	deleted, err := cfg.DB.DeleteFeed(r.Context(), feedID)
+	if err != nil {
+		log.Printf("Error deleting feed: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Feed not found")
+		return
+	}
+	log.Printf("Admin %v deleted feed %v", user.ID, feedID)
+	respondWithJSON(w, 200, "OK")
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Length of the visible part of a key, kept so users can tell their keys apart
+const apiKeyPrefixLength = 8
+
+// Scopes an API key can be limited to. Routes declare the scopes they need in main()
+const (
+	scopeAll          = "*"
+	scopePostsRead    = "posts:read"
+	scopePostsWrite   = "posts:write"
+	scopeFeedsWrite   = "feeds:write"
+	scopeFollowsRead  = "follows:read"
+	scopeFollowsWrite = "follows:write"
+	scopeKeysWrite    = "keys:write"
+	scopeAccount      = "account"
+	scopeAdmin        = "admin"
+)
+
+var validScopes = map[string]bool{
+	scopeAll:          true,
+	scopePostsRead:    true,
+	scopePostsWrite:   true,
+	scopeFeedsWrite:   true,
+	scopeFollowsRead:  true,
+	scopeFollowsWrite: true,
+	scopeKeysWrite:    true,
+	scopeAccount:      true,
+	scopeAdmin:        true,
+}
+
+// Used in databaseAPIKeyToAPIKey(). Key is only set when the key is first created, since only its hash is stored
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// Creates a named API key for the authenticated user
+func (cfg *apiConfig) handlerAPIKeysPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name      string     `json:"name"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		Scopes    []string   `json:"scopes"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if params.Name == "" {
+		respondWithError(w, 400, "Name cannot be empty")
+		return
+	}
+	// Keys get the same access as the key creating them unless they're limited to specific scopes,
+	// & can't be given scopes the creating key doesn't have
+	granted := grantedScopes(r)
+	if len(params.Scopes) == 0 {
+		params.Scopes = granted
+	}
+	for _, scope := range params.Scopes {
+		if !validScopes[scope] {
+			respondWithError(w, 400, fmt.Sprintf("Unknown scope: %v", scope))
+			return
+		}
+	}
+	if scope := missingScope(granted, params.Scopes); scope != "" {
+		respondWithMissingScope(w, scope)
+		return
+	}
+	var expiresAt sql.NullTime
+	if params.ExpiresAt != nil {
+		if params.ExpiresAt.Before(time.Now()) {
+			respondWithError(w, 400, "Expiry must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	apiKey, err := createAPIKey(r.Context(), cfg.DB, user.ID, params.Name, expiresAt, params.Scopes)
+	if err != nil {
+		log.Printf("Error creating API key: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 201, apiKey)
+}
+
+// Lists the authenticated user's API keys, without the keys themselves
+func (cfg *apiConfig) handlerAPIKeysGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	keys, err := cfg.DB.GetAPIKeysByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	payload := []APIKey{}
+	for _, key := range keys {
+		payload = append(payload, databaseAPIKeyToAPIKey(key))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Revokes one of the authenticated user's API keys
+func (cfg *apiConfig) handlerAPIKeysDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	keyID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid APIKeyID")
+		return
+	}
+
+	key, err := cfg.DB.GetAPIKey(r.Context(), database.GetAPIKeyParams{
+		ID:     keyID,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "API key not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	// Revoking the last working key would lock a user without a password out of their account.
+	// Expired keys don't count, they can always be revoked
+	active := !key.ExpiresAt.Valid || key.ExpiresAt.Time.After(time.Now())
+	if active && !user.PasswordHash.Valid {
+		activeKeys, err := cfg.DB.CountActiveAPIKeysByUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		if activeKeys <= 1 {
+			respondWithError(w, 400, "Cannot revoke your only active API key")
+			return
+		}
+	}
+
+	deleted, err := cfg.DB.DeleteAPIKey(r.Context(), database.DeleteAPIKeyParams{
+		ID:     keyID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Error revoking API key: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "API key not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Generates a new API key & stores its hash. The returned APIKey is the only place the raw key is ever available
+func createAPIKey(ctx context.Context, q *database.Queries, userID uuid.UUID, name string, expiresAt sql.NullTime, scopes []string) (APIKey, error) {
+	key, err := generateToken()
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	dbKey, err := q.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		KeyHash:   hashToken(key),
+		KeyPrefix: key[:apiKeyPrefixLength],
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+		Scopes:    scopes,
+	})
+	if err != nil {
+		return APIKey{}, err
+	}
+	apiKey := databaseAPIKeyToAPIKey(dbKey)
+	apiKey.Key = key
+	return apiKey, nil
+}
+
+// Context key for the scopes the request's credentials were granted, set by middlewareAuth
+type grantedScopesKey struct{}
+
+// Scopes granted to an authenticated request's credentials. Sessions are granted every scope
+func grantedScopes(r *http.Request) []string {
+	scopes, _ := r.Context().Value(grantedScopesKey{}).([]string)
+	return scopes
+}
+
+// Responds with 403 & the scope the request's API key is missing
+func respondWithMissingScope(w http.ResponseWriter, scope string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`ApiKey realm=%q, error="insufficient_scope", scope=%q`, authRealm, scope))
+	respondWithJSON(w, 403, errorResponse{
+		Error:        fmt.Sprintf("API key is missing scope: %v", scope),
+		Code:         "insufficient_scope",
+		MissingScope: scope,
+	})
+}
+
+// Returns the first of the required scopes that a key's scopes don't grant, or "" if they're all granted
+func missingScope(granted []string, required []string) string {
+	for _, scope := range required {
+		found := false
+		for _, g := range granted {
+			if g == scopeAll || g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return scope
+		}
+	}
+	return ""
+}
+
+// Helper func that converts database.ApiKey to APIKey, leaving out the key itself
+func databaseAPIKeyToAPIKey(dbKey database.ApiKey) APIKey {
+	key := APIKey{
+		ID:        dbKey.ID,
+		Name:      dbKey.Name,
+		Prefix:    dbKey.KeyPrefix,
+		Scopes:    dbKey.Scopes,
+		CreatedAt: dbKey.CreatedAt,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbKey.LastUsedAt.Valid {
+		key.LastUsedAt = &dbKey.LastUsedAt.Time
+	}
+	if dbKey.ExpiresAt.Valid {
+		key.ExpiresAt = &dbKey.ExpiresAt.Time
+	}
+	return key
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Lifetimes of session tokens. Access tokens are short-lived, refresh tokens are used to get new ones
+const (
+	accessTokenTTL    = 15 * time.Minute
+	refreshTokenTTL   = 30 * 24 * time.Hour
+	minPasswordLength = 8
+)
+
+// bcrypt hash (at bcrypt.DefaultCost) of a password nobody uses. Logins for unknown emails are checked against it,
+// so they take as long as logins for real ones
+const dummyPasswordHash = "$2a$10$d0WUWgZHS1zdPDfiy3aYweQdeqDNRnNhI4Kam6cp71XkYcYFnB9Sy"
+
+// Returned when logging in or refreshing a session
+type sessionTokens struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+}
+
+// Generates a random 32 byte token, hex encoded
+func generateToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate token: %v", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// SHA-256 of a token, hex encoded, which is what's stored in the DB.
+// Tokens are random 32 byte values, so they don't need a slow or salted hash
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Lowercases & validates an email address
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", errors.New("invalid email")
+	}
+	return email, nil
+}
+
+// Hashes a password with bcrypt, after checking it's long enough
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %v characters", minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Generates an access & refresh token pair
+func newSessionTokens(user database.User) (sessionTokens, error) {
+	accessToken, err := generateToken()
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	refreshToken, err := generateToken()
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	return sessionTokens{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  time.Now().Add(accessTokenTTL),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: time.Now().Add(refreshTokenTTL),
+		User:             databaseUserToUser(user),
+	}, nil
+}
+
+// Starts a new session for a user
+func (cfg *apiConfig) createSession(ctx context.Context, user database.User) (sessionTokens, error) {
+	tokens, err := newSessionTokens(user)
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	_, err = cfg.DB.CreateSession(ctx, database.CreateSessionParams{
+		ID:               uuid.New(),
+		UserID:           user.ID,
+		AccessTokenHash:  hashToken(tokens.AccessToken),
+		AccessExpiresAt:  tokens.AccessExpiresAt,
+		RefreshTokenHash: hashToken(tokens.RefreshToken),
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	})
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	return tokens, nil
+}
+
+// Logs in with an email & password
+func (cfg *apiConfig) handlerLoginPost(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	email, err := normalizeEmail(params.Email)
+	if err != nil {
+		respondWithErrorCode(w, 401, "invalid_credentials", "Incorrect email or password")
+		return
+	}
+
+	user, err := cfg.DB.GetUserByEmail(r.Context(), sql.NullString{String: email, Valid: true})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error getting user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	// Users without a password can only use API keys
+	passwordHash := dummyPasswordHash
+	if err == nil && user.PasswordHash.Valid {
+		passwordHash = user.PasswordHash.String
+	}
+	passwordMatches := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(params.Password)) == nil
+	if err != nil || !user.PasswordHash.Valid || !passwordMatches {
+		respondWithErrorCode(w, 401, "invalid_credentials", "Incorrect email or password")
+		return
+	}
+	if user.DisabledAt.Valid {
+		respondWithErrorCode(w, 403, "account_disabled", "Account is disabled")
+		return
+	}
+
+	tokens, err := cfg.createSession(r.Context(), user)
+	if err != nil {
+		log.Printf("Error creating session: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, tokens)
+}
+
+// Exchanges a refresh token for a new token pair. The old refresh token stops working
+func (cfg *apiConfig) handlerRefreshPost(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	session, err := cfg.DB.GetSessionByRefreshToken(r.Context(), hashToken(params.RefreshToken))
+	// Impersonation sessions are access-only
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (session.RefreshExpiresAt.Before(time.Now()) || session.ImpersonatedBy.Valid)) {
+		respondWithErrorCode(w, 401, "invalid_credentials", "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting session: %s", err)
+		respondWithError(w, 503, "Authentication is temporarily unavailable")
+		return
+	}
+	user, err := cfg.DB.GetUserByID(r.Context(), session.UserID)
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if user.DisabledAt.Valid {
+		respondWithErrorCode(w, 403, "account_disabled", "Account is disabled")
+		return
+	}
+
+	tokens, err := newSessionTokens(user)
+	if err != nil {
+		log.Printf("Error generating tokens: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	// Only succeeds if the refresh token wasn't already used by a concurrent request
+	_, err = cfg.DB.RotateSession(r.Context(), database.RotateSessionParams{
+		ID:                  session.ID,
+		OldRefreshTokenHash: session.RefreshTokenHash,
+		AccessTokenHash:     hashToken(tokens.AccessToken),
+		AccessExpiresAt:     tokens.AccessExpiresAt,
+		RefreshTokenHash:    hashToken(tokens.RefreshToken),
+		RefreshExpiresAt:    tokens.RefreshExpiresAt,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithErrorCode(w, 401, "invalid_credentials", "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error rotating session: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, tokens)
+}
+
+// Ends the session the request was made with
+func (cfg *apiConfig) handlerLogoutPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		respondWithError(w, 400, "Logging out requires a session token")
+		return
+	}
+	err := cfg.DB.DeleteSessionByAccessToken(r.Context(), hashToken(accessToken))
+	if err != nil {
+		log.Printf("Error deleting session: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Sets or changes the authenticated user's password & ends their other sessions
+func (cfg *apiConfig) handlerUsersPasswordPut(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if !user.Email.Valid {
+		respondWithError(w, 400, "An email is required before setting a password")
+		return
+	}
+	if user.PasswordHash.Valid && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(params.CurrentPassword)) != nil {
+		respondWithError(w, 403, "Current password is incorrect")
+		return
+	}
+	passwordHash, err := hashPassword(params.NewPassword)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	err = cfg.DB.SetUserPassword(r.Context(), database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: sql.NullString{String: passwordHash, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Error setting password: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	// The current session, if there is one, stays logged in
+	currentToken, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	err = cfg.DB.DeleteOtherSessions(r.Context(), database.DeleteOtherSessionsParams{
+		UserID:          user.ID,
+		AccessTokenHash: hashToken(currentToken),
+	})
+	if err != nil {
+		log.Printf("Error deleting sessions: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Realm sent in WWW-Authenticate challenges
+const authRealm = "kFeed"
+
+// Who a request was made by & what it's allowed to do. Sessions have full access.
+// Key identifies the session or API key used, e.g. for rate limiting
+type credentials struct {
+	User   database.User
+	Scopes []string
+	Key    string
+}
+
+// Why a request couldn't be authenticated. Scheme is the auth scheme the challenge is for
+type authError struct {
+	Status  int
+	Code    string
+	Message string
+	Scheme  string
+}
+
+// Authenticates a request from its Authorization header, either "Bearer <access token>" or "ApiKey <key>"
+func (cfg *apiConfig) authenticate(r *http.Request) (credentials, *authError) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return credentials{}, &authError{Status: 401, Code: "missing_credentials", Message: "Missing Authorization header"}
+	}
+	scheme, token, _ := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if token == "" || (scheme != "Bearer" && scheme != "ApiKey") {
+		return credentials{}, &authError{Status: 401, Code: "malformed_credentials", Message: `Authorization header must be "Bearer <token>" or "ApiKey <key>"`}
+	}
+	invalid := &authError{Status: 401, Code: "invalid_credentials", Message: "Invalid or expired credentials", Scheme: scheme}
+
+	var userID uuid.UUID
+	var keyID uuid.UUID
+	var credKey string
+	scopes := []string{scopeAll}
+	if scheme == "Bearer" {
+		session, err := cfg.DB.GetSessionByAccessToken(r.Context(), hashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return credentials{}, invalid
+		}
+		if err != nil {
+			log.Printf("Error getting session: %s", err)
+			return credentials{}, &authError{Status: 503, Code: "service_unavailable", Message: "Authentication is temporarily unavailable"}
+		}
+		if session.AccessExpiresAt.Before(time.Now()) {
+			return credentials{}, invalid
+		}
+		userID = session.UserID
+		credKey = "session:" + session.ID.String()
+	} else {
+		// Keys are looked up by their visible prefix, then the stored hashes are compared in constant time,
+		// so how long the check takes doesn't depend on the secret part of the key
+		if len(token) < apiKeyPrefixLength {
+			return credentials{}, invalid
+		}
+		candidates, err := cfg.DB.GetAPIKeysByPrefix(r.Context(), token[:apiKeyPrefixLength])
+		if err != nil {
+			log.Printf("Error getting API key: %s", err)
+			return credentials{}, &authError{Status: 503, Code: "service_unavailable", Message: "Authentication is temporarily unavailable"}
+		}
+		keyHash := []byte(hashToken(token))
+		var key database.ApiKey
+		found := false
+		for _, candidate := range candidates {
+			if subtle.ConstantTimeCompare([]byte(candidate.KeyHash), keyHash) == 1 {
+				key = candidate
+				found = true
+			}
+		}
+		if !found || (key.ExpiresAt.Valid && key.ExpiresAt.Time.Before(time.Now())) {
+			return credentials{}, invalid
+		}
+		userID = key.UserID
+		keyID = key.ID
+		credKey = "key:" + key.ID.String()
+		scopes = key.Scopes
+	}
+
+	user, err := cfg.DB.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return credentials{}, invalid
+	}
+	if err != nil {
+		log.Printf("Error getting user: %s", err)
+		return credentials{}, &authError{Status: 503, Code: "service_unavailable", Message: "Authentication is temporarily unavailable"}
+	}
+	if user.DisabledAt.Valid {
+		return credentials{}, &authError{Status: 403, Code: "account_disabled", Message: "Account is disabled"}
+	}
+	if keyID != uuid.Nil {
+		cfg.DB.TouchAPIKey(r.Context(), keyID)
+	}
+	return credentials{User: user, Scopes: scopes, Key: credKey}, nil
+}
+
+// Responds to a request that failed authentication. 401s carry a challenge for each scheme we accept
+func respondWithAuthError(w http.ResponseWriter, authErr *authError) {
+	if authErr.Status == 401 {
+		// Per RFC 6750 the error param is left out when no credentials were sent
+		challengeError := "invalid_token"
+		if authErr.Code == "malformed_credentials" {
+			challengeError = "invalid_request"
+		}
+		for _, scheme := range []string{"Bearer", "ApiKey"} {
+			challenge := fmt.Sprintf("%v realm=%q", scheme, authRealm)
+			if authErr.Code != "missing_credentials" && (authErr.Scheme == "" || authErr.Scheme == scheme) {
+				challenge += fmt.Sprintf(`, error=%q`, challengeError)
+			}
+			w.Header().Add("WWW-Authenticate", challenge)
+		}
+	}
+	respondWithErrorCode(w, authErr.Status, authErr.Code, authErr.Message)
+}
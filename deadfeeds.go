@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Dormant feeds are still fetched, but only this often
+const dormantFetchInterval = 24 * time.Hour
+
+// Background goroutine that flags feeds without new posts as dormant, once an hour
+func (cfg *apiConfig) dormantFeedsWorker() {
+	ctx := context.TODO()
+	ticker := time.Tick(time.Hour)
+	for {
+		<-ticker
+
+		marked, err := cfg.DB.MarkDormantFeeds(ctx, time.Now().AddDate(0, 0, -cfg.DormantAfterDays))
+		if err != nil {
+			fmt.Printf("Error marking dormant feeds: %v\n", err)
+			continue
+		}
+		if marked > 0 {
+			fmt.Printf("Marked %v feeds as dormant\n", marked)
+		}
+	}
+}
+
+// Lists feeds that nobody follows & that hold no starred posts, along with how many posts they hold
+func (cfg *apiConfig) handlerOrphanedFeedsGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := cfg.DB.GetOrphanedFeeds(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	type orphanedFeed struct {
+		Feed
+		PostCount int64 `json:"post_count"`
+	}
+	payload := []orphanedFeed{}
+	for _, row := range feeds {
+		feed := databaseFeedToFeed(database.Feed{
+			ID:                  row.ID,
+			CreatedAt:           row.CreatedAt,
+			UpdatedAt:           row.UpdatedAt,
+			Name:                row.Name,
+			Url:                 row.Url,
+			UserID:              row.UserID,
+			LastFetchedAt:       row.LastFetchedAt,
+			FetchFullText:       row.FetchFullText,
+			RetentionKeep:       row.RetentionKeep,
+			RetentionMaxAgeDays: row.RetentionMaxAgeDays,
+			LastPostAt:          row.LastPostAt,
+			Dormant:             row.Dormant,
+		})
+		payload = append(payload, orphanedFeed{Feed: feed, PostCount: row.PostCount})
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Deletes every feed that nobody follows, except ones with starred posts. Their posts are removed along with them
+func (cfg *apiConfig) handlerOrphanedFeedsDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	deleted, err := cfg.DB.DeleteOrphanedFeeds(r.Context())
+	if err != nil {
+		log.Printf("Error deleting orphaned feeds: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	response := struct {
+		Deleted int64 `json:"deleted"`
+	}{
+		Deleted: deleted,
+	}
+	respondWithJSON(w, 200, response)
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Exports with more rows than this are built by a background job instead of in the request.
+// Finished jobs are kept for a week
+const (
+	exportInlineMaxRows = 5000
+	exportJobTTL        = 7 * 24 * time.Hour
+)
+
+// Everything stored about a user, as it's written to the archive
+type userExport struct {
+	Profile      User
+	Follows      []exportFollow
+	CreatedFeeds []Feed
+	APIKeys      []APIKey
+	ReadState    []exportReadState
+	ReadMarks    []exportReadMark
+	Starred      []exportStarredPost
+	Folders      []exportFolder
+	Searches     []exportSavedSearch
+	FilterRules  []FilterRule
+	Tags         []exportPostTag
+}
+
+// A followed feed, as it appears in follows.json
+type exportFollow struct {
+	FeedID         uuid.UUID `json:"feed_id"`
+	FeedName       string    `json:"feed_name"`
+	FeedURL        string    `json:"feed_url"`
+	FollowedAt     time.Time `json:"followed_at"`
+	Title          *string   `json:"title"`
+	Notes          string    `json:"notes"`
+	Priority       int32     `json:"priority"`
+	ShowInTimeline bool      `json:"show_in_timeline"`
+}
+
+// A post the user marked read or unread, as it appears in read_state.json
+type exportReadState struct {
+	PostID    uuid.UUID `json:"post_id"`
+	PostURL   string    `json:"post_url"`
+	Read      bool      `json:"read"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// A feed the user marked as read up to a point in time, as it appears in read_watermarks.json
+type exportReadMark struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	FeedURL    string    `json:"feed_url"`
+	ReadBefore time.Time `json:"read_before"`
+}
+
+// A starred post, as it appears in starred.json
+type exportStarredPost struct {
+	PostID    uuid.UUID `json:"post_id"`
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	StarredAt time.Time `json:"starred_at"`
+}
+
+// A tag on a post, as it appears in tags.json
+type exportPostTag struct {
+	Tag      string    `json:"tag"`
+	PostID   uuid.UUID `json:"post_id"`
+	PostURL  string    `json:"post_url"`
+	TaggedAt time.Time `json:"tagged_at"`
+}
+
+// A folder & the feeds filed in it, as it appears in folders.json
+type exportFolder struct {
+	Name    string      `json:"name"`
+	FeedIDs []uuid.UUID `json:"feed_ids"`
+}
+
+// A saved search, as it appears in saved_searches.json. The folder is referenced by name, like in folders.json
+type exportSavedSearch struct {
+	Name    string     `json:"name"`
+	Query   string     `json:"query"`
+	FeedID  *uuid.UUID `json:"feed_id"`
+	Folder  *string    `json:"folder"`
+	Since   *time.Time `json:"since"`
+	Until   *time.Time `json:"until"`
+	Starred *bool      `json:"starred"`
+	Tag     *string    `json:"tag"`
+	Sort    string     `json:"sort"`
+}
+
+// Used in databaseExportJobToExportJob()
+type ExportJob struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Created string        `xml:"head>dateCreated"`
+	Feeds   []opmlOutline `xml:"body>outline"`
+}
+
+// A feed, or a folder of feeds
+type opmlOutline struct {
+	Type     string        `xml:"type,attr,omitempty"`
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// Exports the authenticated user's data as a ZIP. Small exports are sent right away, large ones
+// (or any with ?async=true) are built in the background & answered with 202 & the job to poll
+func (cfg *apiConfig) handlerUsersExportGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := cfg.exportSize(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	if rows <= exportInlineMaxRows && r.URL.Query().Get("async") != "true" {
+		export, err := cfg.collectExport(r.Context(), user)
+		if err != nil {
+			log.Printf("Error exporting user: %s", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+		setExportHeaders(w, time.Now())
+		if err := export.writeZip(w); err != nil {
+			log.Printf("Error writing export: %s", err)
+		}
+		return
+	}
+
+	// Old exports are cleaned up whenever a new one is requested
+	if _, err := cfg.DB.DeleteExpiredExportJobs(r.Context(), time.Now().Add(-exportJobTTL)); err != nil {
+		log.Printf("Error deleting expired exports: %s", err)
+	}
+	job, err := cfg.DB.CreateExportJob(r.Context(), database.CreateExportJobParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating export job: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	go cfg.runExportJob(job.ID, user)
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/users/export/%v", job.ID))
+	respondWithJSON(w, 202, databaseExportJobToExportJob(job))
+}
+
+// Retrieves the status of one of the authenticated user's export jobs
+func (cfg *apiConfig) handlerUsersExportJobGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	job, ok := cfg.getExportJob(w, r, user)
+	if !ok {
+		return
+	}
+	respondWithJSON(w, 200, databaseExportJobToExportJob(job))
+}
+
+// Downloads the archive of a completed export job
+func (cfg *apiConfig) handlerUsersExportJobDownloadGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	job, ok := cfg.getExportJob(w, r, user)
+	if !ok {
+		return
+	}
+	if job.Status != "completed" {
+		respondWithError(w, 409, fmt.Sprintf("Export is %v", job.Status))
+		return
+	}
+	setExportHeaders(w, job.CreatedAt)
+	w.Write(job.Data)
+}
+
+// Looks up the export job in the URL, responding with an error if it isn't one of the user's
+func (cfg *apiConfig) getExportJob(w http.ResponseWriter, r *http.Request, user database.User) (database.ExportJob, bool) {
+	jobID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid ExportJobID")
+		return database.ExportJob{}, false
+	}
+	job, err := cfg.DB.GetExportJob(r.Context(), database.GetExportJobParams{
+		ID:     jobID,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Export not found")
+		return database.ExportJob{}, false
+	}
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return database.ExportJob{}, false
+	}
+	return job, true
+}
+
+// Builds an export in the background & stores the archive on the job
+func (cfg *apiConfig) runExportJob(jobID uuid.UUID, user database.User) {
+	ctx := context.TODO()
+	var buf bytes.Buffer
+	export, err := cfg.collectExport(ctx, user)
+	if err == nil {
+		err = export.writeZip(&buf)
+	}
+	if err != nil {
+		fmt.Printf("Error exporting user %v: %v\n", user.ID, err)
+		err = cfg.DB.FailExportJob(ctx, database.FailExportJobParams{
+			ID:    jobID,
+			Error: sql.NullString{String: "Export failed", Valid: true},
+		})
+		if err != nil {
+			fmt.Printf("Error updating export job: %v\n", err)
+		}
+		return
+	}
+	err = cfg.DB.CompleteExportJob(ctx, database.CompleteExportJobParams{
+		ID:   jobID,
+		Data: buf.Bytes(),
+	})
+	if err != nil {
+		fmt.Printf("Error updating export job: %v\n", err)
+	}
+}
+
+// Roughly how many rows an export holds, used to decide whether to build it in the background
+func (cfg *apiConfig) exportSize(ctx context.Context, userID uuid.UUID) (int64, error) {
+	follows, err := cfg.DB.CountFollowedFeeds(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	states, err := cfg.DB.CountUserPostStates(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	starred, err := cfg.DB.CountStarredPosts(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	tags, err := cfg.DB.CountPostTags(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return follows + states + starred + tags, nil
+}
+
+// Loads everything that goes into a user's export
+func (cfg *apiConfig) collectExport(ctx context.Context, user database.User) (userExport, error) {
+	export := userExport{
+		Profile:      databaseUserToUser(user),
+		Follows:      []exportFollow{},
+		CreatedFeeds: []Feed{},
+		APIKeys:      []APIKey{},
+		ReadState:    []exportReadState{},
+		ReadMarks:    []exportReadMark{},
+		Starred:      []exportStarredPost{},
+		Folders:      []exportFolder{},
+		Searches:     []exportSavedSearch{},
+		FilterRules:  []FilterRule{},
+		Tags:         []exportPostTag{},
+	}
+
+	follows, err := cfg.DB.GetFollowedFeedsWithDetails(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get follows: %v", err)
+	}
+	for _, follow := range follows {
+		exported := exportFollow{
+			FeedID:         follow.FeedID,
+			FeedName:       follow.FeedName,
+			FeedURL:        follow.FeedUrl,
+			FollowedAt:     follow.CreatedAt,
+			Notes:          follow.Notes,
+			Priority:       follow.Priority,
+			ShowInTimeline: follow.ShowInTimeline,
+		}
+		// If NULL, keep the zero value (nil)
+		if follow.Title.Valid {
+			title := follow.Title.String
+			exported.Title = &title
+		}
+		export.Follows = append(export.Follows, exported)
+	}
+
+	feeds, err := cfg.DB.GetFeedsByCreator(ctx, uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		return userExport{}, fmt.Errorf("get feeds: %v", err)
+	}
+	for _, feed := range feeds {
+		export.CreatedFeeds = append(export.CreatedFeeds, databaseFeedToFeed(feed))
+	}
+
+	keys, err := cfg.DB.GetAPIKeysByUser(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get API keys: %v", err)
+	}
+	for _, key := range keys {
+		export.APIKeys = append(export.APIKeys, databaseAPIKeyToAPIKey(key))
+	}
+
+	states, err := cfg.DB.GetUserPostStates(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get read state: %v", err)
+	}
+	for _, state := range states {
+		export.ReadState = append(export.ReadState, exportReadState{
+			PostID:    state.PostID,
+			PostURL:   state.PostUrl,
+			Read:      state.IsRead,
+			UpdatedAt: state.UpdatedAt,
+		})
+	}
+
+	marks, err := cfg.DB.GetReadWatermarksByUser(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get read watermarks: %v", err)
+	}
+	for _, mark := range marks {
+		export.ReadMarks = append(export.ReadMarks, exportReadMark{
+			FeedID:     mark.FeedID,
+			FeedURL:    mark.FeedUrl,
+			ReadBefore: mark.ReadBefore,
+		})
+	}
+
+	starred, err := cfg.DB.GetStarredPostsForExport(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get starred posts: %v", err)
+	}
+	for _, star := range starred {
+		export.Starred = append(export.Starred, exportStarredPost{
+			PostID:    star.PostID,
+			Title:     star.PostTitle,
+			URL:       star.PostUrl,
+			StarredAt: star.CreatedAt,
+		})
+	}
+
+	folders, err := cfg.DB.GetFoldersByUser(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get folders: %v", err)
+	}
+	assignments, err := cfg.DB.GetFolderAssignmentsByUser(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get folder assignments: %v", err)
+	}
+	for _, folder := range folders {
+		exported := exportFolder{Name: folder.Name, FeedIDs: []uuid.UUID{}}
+		for _, assignment := range assignments {
+			if assignment.FolderID == folder.ID {
+				exported.FeedIDs = append(exported.FeedIDs, assignment.FeedID)
+			}
+		}
+		export.Folders = append(export.Folders, exported)
+	}
+
+	savedSearches, err := cfg.DB.GetSavedSearchesByUser(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get saved searches: %v", err)
+	}
+	for _, savedSearch := range savedSearches {
+		converted := databaseSavedSearchToSavedSearch(savedSearch, 0)
+		exported := exportSavedSearch{
+			Name:    savedSearch.Name,
+			Query:   savedSearch.Query,
+			FeedID:  converted.FeedID,
+			Since:   converted.Since,
+			Until:   converted.Until,
+			Starred: converted.Starred,
+			Tag:     converted.Tag,
+			Sort:    converted.Sort,
+		}
+		for _, folder := range folders {
+			if savedSearch.FolderID.Valid && folder.ID == savedSearch.FolderID.UUID {
+				name := folder.Name
+				exported.Folder = &name
+			}
+		}
+		export.Searches = append(export.Searches, exported)
+	}
+
+	rules, err := cfg.DB.GetFilterRulesByUser(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get filter rules: %v", err)
+	}
+	for _, rule := range rules {
+		export.FilterRules = append(export.FilterRules, databaseFilterRuleToFilterRule(rule))
+	}
+
+	tags, err := cfg.DB.GetPostTagsForExport(ctx, user.ID)
+	if err != nil {
+		return userExport{}, fmt.Errorf("get tags: %v", err)
+	}
+	for _, tag := range tags {
+		export.Tags = append(export.Tags, exportPostTag{
+			Tag:      tag.Tag,
+			PostID:   tag.PostID,
+			PostURL:  tag.PostUrl,
+			TaggedAt: tag.CreatedAt,
+		})
+	}
+	return export, nil
+}
+
+// Writes the export as a ZIP of JSON files, plus the followed feeds as OPML
+func (export userExport) writeZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	addJSON := func(name string, v any) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	if err := addJSON("profile.json", export.Profile); err != nil {
+		return err
+	}
+	if err := addJSON("follows.json", export.Follows); err != nil {
+		return err
+	}
+	if err := addJSON("feeds.json", export.CreatedFeeds); err != nil {
+		return err
+	}
+	if err := addJSON("api_keys.json", export.APIKeys); err != nil {
+		return err
+	}
+	if err := addJSON("read_state.json", export.ReadState); err != nil {
+		return err
+	}
+	if err := addJSON("read_watermarks.json", export.ReadMarks); err != nil {
+		return err
+	}
+	if err := addJSON("starred.json", export.Starred); err != nil {
+		return err
+	}
+	if err := addJSON("folders.json", export.Folders); err != nil {
+		return err
+	}
+	if err := addJSON("saved_searches.json", export.Searches); err != nil {
+		return err
+	}
+	if err := addJSON("filter_rules.json", export.FilterRules); err != nil {
+		return err
+	}
+	if err := addJSON("tags.json", export.Tags); err != nil {
+		return err
+	}
+
+	doc := opmlDocument{
+		Version: "2.0",
+		Title:   fmt.Sprintf("%v's subscriptions", export.Profile.Name),
+		Created: time.Now().UTC().Format(time.RFC1123Z),
+	}
+	// Feeds are nested under each folder they're in, feeds without a folder are at the top level
+	feedOutlines := map[uuid.UUID]opmlOutline{}
+	filed := map[uuid.UUID]bool{}
+	for _, follow := range export.Follows {
+		name := follow.FeedName
+		if follow.Title != nil {
+			name = *follow.Title
+		}
+		feedOutlines[follow.FeedID] = opmlOutline{
+			Type:   "rss",
+			Text:   name,
+			Title:  name,
+			XMLURL: follow.FeedURL,
+		}
+	}
+	for _, folder := range export.Folders {
+		folderOutline := opmlOutline{Text: folder.Name, Title: folder.Name}
+		for _, feedID := range folder.FeedIDs {
+			folderOutline.Outlines = append(folderOutline.Outlines, feedOutlines[feedID])
+			filed[feedID] = true
+		}
+		doc.Feeds = append(doc.Feeds, folderOutline)
+	}
+	for _, follow := range export.Follows {
+		if !filed[follow.FeedID] {
+			doc.Feeds = append(doc.Feeds, feedOutlines[follow.FeedID])
+		}
+	}
+	f, err := zw.Create("subscriptions.opml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Headers for sending an export archive as a download
+func setExportHeaders(w http.ResponseWriter, createdAt time.Time) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="kfeed-export-%v.zip"`, createdAt.Format("2006-01-02")))
+}
+
+// Helper func that converts database.ExportJob to ExportJob, leaving out the archive itself
+func databaseExportJobToExportJob(dbJob database.ExportJob) ExportJob {
+	job := ExportJob{
+		ID:        dbJob.ID,
+		Status:    dbJob.Status,
+		CreatedAt: dbJob.CreatedAt,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbJob.Error.Valid {
+		job.Error = &dbJob.Error.String
+	}
+	if dbJob.CompletedAt.Valid {
+		job.CompletedAt = &dbJob.CompletedAt.Time
+	}
+	if dbJob.Status == "completed" {
+		job.DownloadURL = fmt.Sprintf("/v1/users/export/%v/download", dbJob.ID)
+	}
+	return job
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// What a filter rule matches against
+var filterFields = map[string]bool{
+	"title":   true,
+	"content": true,
+	"author":  true,
+	"feed":    true,
+}
+
+// Keywords match case-insensitively anywhere in the field, regexes use Go's syntax (prefix with (?i) to ignore case)
+var filterMatchTypes = map[string]bool{
+	"keyword": true,
+	"regex":   true,
+}
+
+// What happens to a matching post. Hidden posts are left out of GET /v1/posts & search, & count as read
+const (
+	filterActionHide     = "hide"
+	filterActionMarkRead = "mark_read"
+	filterActionStar     = "star"
+	filterActionTag      = "tag"
+)
+
+var filterActions = map[string]bool{
+	filterActionHide:     true,
+	filterActionMarkRead: true,
+	filterActionStar:     true,
+	filterActionTag:      true,
+}
+
+// How many of the user's newest posts a rule is tested against
+const filterTestPosts = 200
+
+// Matches HTML tags, so content is matched on its text
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// Used in databaseFilterRuleToFilterRule()
+type FilterRule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Field     string    `json:"field"`
+	MatchType string    `json:"match_type"`
+	Pattern   string    `json:"pattern"`
+	Action    string    `json:"action"`
+	Tag       *string   `json:"tag"`
+}
+
+// The parts of a post a rule can match. Content is the post's body without HTML, see postBody()
+type filterInput struct {
+	Title   string
+	Content string
+	Author  string
+	Feed    string
+}
+
+// A rule ready to be matched, so regexes are only compiled once per feed fetch
+type compiledFilterRule struct {
+	database.GetFilterRulesForFeedRow
+	matches func(string) bool
+}
+
+// Body of POST /v1/filter_rules & POST /v1/filter_rules/test
+type filterRuleParameters struct {
+	Field     string `json:"field"`
+	MatchType string `json:"match_type"`
+	Pattern   string `json:"pattern"`
+	Action    string `json:"action"`
+	Tag       string `json:"tag"`
+}
+
+// Creates a filter rule, applied to posts fetched from now on
+func (cfg *apiConfig) handlerFilterRulesPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	decoder := json.NewDecoder(r.Body)
+	params := filterRuleParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if _, ok := validateFilterRule(w, params); !ok {
+		return
+	}
+	if !filterActions[params.Action] {
+		respondWithError(w, 400, "action must be hide, mark_read, star or tag")
+		return
+	}
+	params.Tag = strings.TrimSpace(params.Tag)
+	if (params.Action == filterActionTag) != (params.Tag != "") {
+		respondWithError(w, 400, "tag must be set for the tag action, & only for it")
+		return
+	}
+
+	rule, err := cfg.DB.CreateFilterRule(r.Context(), database.CreateFilterRuleParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Field:     params.Field,
+		MatchType: params.MatchType,
+		Pattern:   params.Pattern,
+		Action:    params.Action,
+		Tag:       sql.NullString{String: params.Tag, Valid: params.Tag != ""},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating filter rule: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 201, databaseFilterRuleToFilterRule(rule))
+}
+
+// Lists the authenticated user's filter rules
+func (cfg *apiConfig) handlerFilterRulesGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	rules, err := cfg.DB.GetFilterRulesByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	payload := []FilterRule{}
+	for _, rule := range rules {
+		payload = append(payload, databaseFilterRuleToFilterRule(rule))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Deletes a filter rule. Posts it hid show up again, other actions aren't undone
+func (cfg *apiConfig) handlerFilterRulesDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	ruleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FilterRuleID")
+		return
+	}
+	deleted, err := cfg.DB.DeleteFilterRule(r.Context(), database.DeleteFilterRuleParams{
+		ID:     ruleID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Error deleting filter rule: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Filter rule not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Dry-runs a rule against the user's newest posts from every followed feed, hidden ones included, & responds with the ones it matches.
+// Takes the same body as creating a rule, the action is ignored
+func (cfg *apiConfig) handlerFilterRulesTestPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	decoder := json.NewDecoder(r.Body)
+	params := filterRuleParameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	matches, ok := validateFilterRule(w, params)
+	if !ok {
+		return
+	}
+
+	posts, err := cfg.DB.GetPostsByUser(r.Context(), database.GetPostsByUserParams{
+		UserID:        user.ID,
+		IncludeHidden: true,
+		// Rules run on every followed feed, not only the ones in the timeline
+		AllFollows: true,
+		LimitCount: filterTestPosts,
+	})
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	titles, err := cfg.feedTitles(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	matched := []Post{}
+	for _, dbPost := range posts {
+		input := filterInputForPost(dbPost.Title, postBody(dbPost.Description, dbPost.Content), dbPost.Author.String, titles[dbPost.FeedID])
+		if !matches(input.field(params.Field)) {
+			continue
+		}
+		post := databasePostToPost(dbPost)
+		post.FeedTitle = titles[post.FeedID]
+		post.Description = cfg.proxyImages(post.Description, post.Url)
+		post.Content = cfg.proxyImages(post.Content, post.Url)
+		matched = append(matched, post)
+	}
+	if len(matched) > 0 {
+		err = cfg.addTags(r.Context(), user.ID, matched)
+		if err != nil {
+			log.Printf("Error getting tags: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+	}
+
+	response := struct {
+		Checked int    `json:"checked"`
+		Matches []Post `json:"matches"`
+	}{
+		Checked: len(posts),
+		Matches: matched,
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Checks a rule's field, match type & pattern, & returns its matcher. Responds with 400 & returns false if it's invalid
+func validateFilterRule(w http.ResponseWriter, params filterRuleParameters) (func(string) bool, bool) {
+	if !filterFields[params.Field] {
+		respondWithError(w, 400, "field must be title, content, author or feed")
+		return nil, false
+	}
+	if !filterMatchTypes[params.MatchType] {
+		respondWithError(w, 400, "match_type must be keyword or regex")
+		return nil, false
+	}
+	if strings.TrimSpace(params.Pattern) == "" {
+		respondWithError(w, 400, "Pattern cannot be empty")
+		return nil, false
+	}
+	matches, err := filterMatcher(params.MatchType, params.Pattern)
+	if err != nil {
+		respondWithError(w, 400, fmt.Sprintf("Invalid regex: %v", err))
+		return nil, false
+	}
+	return matches, true
+}
+
+// Builds the function that tests a field's value against a pattern
+func filterMatcher(matchType string, pattern string) (func(string) bool, error) {
+	if matchType == "regex" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	keyword := strings.ToLower(pattern)
+	return func(value string) bool {
+		return strings.Contains(strings.ToLower(value), keyword)
+	}, nil
+}
+
+func filterInputForPost(title, body, author, feedTitle string) filterInput {
+	return filterInput{
+		Title:   title,
+		Content: htmlTagPattern.ReplaceAllString(body, " "),
+		Author:  author,
+		Feed:    feedTitle,
+	}
+}
+
+// What "content" rules match: the extracted article for feeds with full text fetching, the feed's description otherwise
+func postBody(description, content sql.NullString) string {
+	if content.Valid {
+		return content.String
+	}
+	return description.String
+}
+
+func (input filterInput) field(name string) string {
+	switch name {
+	case "title":
+		return input.Title
+	case "content":
+		return input.Content
+	case "author":
+		return input.Author
+	case "feed":
+		return input.Feed
+	}
+	return ""
+}
+
+// Loads & compiles the rules of everyone who follows a feed. Rules that no longer compile are skipped
+func (cfg *apiConfig) loadFilterRules(ctx context.Context, feedID uuid.UUID) ([]compiledFilterRule, error) {
+	rows, err := cfg.DB.GetFilterRulesForFeed(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+	rules := []compiledFilterRule{}
+	for _, row := range rows {
+		matches, err := filterMatcher(row.MatchType, row.Pattern)
+		if err != nil {
+			fmt.Printf("Error compiling filter rule %v: %v\n", row.ID, err)
+			continue
+		}
+		rules = append(rules, compiledFilterRule{GetFilterRulesForFeedRow: row, matches: matches})
+	}
+	return rules, nil
+}
+
+// Runs a feed's rules on a newly fetched post, applying the actions of the ones that match.
+// content is the post's full text, if it was fetched
+func (cfg *apiConfig) applyFilterRules(ctx context.Context, rules []compiledFilterRule, post database.AddPostParams, content sql.NullString) {
+	for _, rule := range rules {
+		input := filterInputForPost(post.Title, postBody(post.Description, content), post.Author.String, rule.FeedTitle)
+		if !rule.matches(input.field(rule.Field)) {
+			continue
+		}
+
+		var err error
+		switch rule.Action {
+		case filterActionHide:
+			err = cfg.DB.HidePost(ctx, database.HidePostParams{RuleID: rule.ID, PostID: post.ID, UserID: rule.UserID})
+			if err != nil {
+				break
+			}
+			// Hidden posts shouldn't keep showing up in unread counts
+			fallthrough
+		case filterActionMarkRead:
+			_, err = cfg.DB.SetPostsReadState(ctx, database.SetPostsReadStateParams{
+				UserID:  rule.UserID,
+				IsRead:  true,
+				PostIds: []uuid.UUID{post.ID},
+			})
+		case filterActionStar:
+			_, err = cfg.DB.StarPost(ctx, database.StarPostParams{UserID: rule.UserID, PostID: post.ID})
+		case filterActionTag:
+			_, err = cfg.DB.TagPost(ctx, database.TagPostParams{UserID: rule.UserID, PostID: post.ID, Tag: rule.Tag.String})
+		}
+		if err != nil {
+			fmt.Printf("Error applying filter rule %v to %v: %v\n", rule.ID, post.Url, err)
+		}
+	}
+}
+
+// Helper func that converts database.FilterRule to FilterRule
+func databaseFilterRuleToFilterRule(dbRule database.FilterRule) FilterRule {
+	rule := FilterRule{
+		ID:        dbRule.ID,
+		CreatedAt: dbRule.CreatedAt,
+		UpdatedAt: dbRule.UpdatedAt,
+		Field:     dbRule.Field,
+		MatchType: dbRule.MatchType,
+		Pattern:   dbRule.Pattern,
+		Action:    dbRule.Action,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbRule.Tag.Valid {
+		rule.Tag = &dbRule.Tag.String
+	}
+	return rule
+}
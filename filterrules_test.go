@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFilterMatcher(t *testing.T) {
+	tests := []struct {
+		name      string
+		matchType string
+		pattern   string
+		value     string
+		want      bool
+	}{
+		{"keyword substring", "keyword", "crypto", "New cryptocurrency exchange opens", true},
+		{"keyword ignores case", "keyword", "CrYpTo", "crypto winter is here", true},
+		{"keyword no match", "keyword", "crypto", "Central bank raises rates", false},
+		{"keyword phrase", "keyword", "sponsored post", "This is a Sponsored Post about shoes", true},
+		{"keyword is literal", "keyword", "a.c", "abc", false},
+		{"regex match", "regex", `^\[Sponsored\]`, "[Sponsored] Buy now", true},
+		{"regex anchored", "regex", `^\[Sponsored\]`, "Not [Sponsored]", false},
+		{"regex is case sensitive", "regex", `sponsored`, "Sponsored", false},
+		{"regex case insensitive flag", "regex", `(?i)sponsored`, "SPONSORED", true},
+		{"regex alternation", "regex", `\b(nft|web3)\b`, "Why web3 failed", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := filterMatcher(tt.matchType, tt.pattern)
+			if err != nil {
+				t.Fatalf("filterMatcher(%q, %q): %v", tt.matchType, tt.pattern, err)
+			}
+			if got := matches(tt.value); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatcherInvalidRegex(t *testing.T) {
+	if _, err := filterMatcher("regex", `(unclosed`); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+	// Keywords aren't parsed, so the same pattern is fine
+	if _, err := filterMatcher("keyword", `(unclosed`); err != nil {
+		t.Errorf("expected keywords to accept any pattern, got %v", err)
+	}
+}
+
+func TestFilterInputForPost(t *testing.T) {
+	input := filterInputForPost(
+		"Weekly roundup",
+		`<p>Deals on <a href="https://shop.example.com">shoes</a></p><img src="x.png">`,
+		"Jane Doe",
+		"Example News",
+	)
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{"title", "Weekly roundup"},
+		{"author", "Jane Doe"},
+		{"feed", "Example News"},
+		{"unknown", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			if got := input.field(tt.field); got != tt.want {
+				t.Errorf("field(%q) = %q, want %q", tt.field, got, tt.want)
+			}
+		})
+	}
+
+	content := input.field("content")
+	if strings.ContainsAny(content, "<>") || strings.Contains(content, "shop.example.com") {
+		t.Errorf("expected content without markup, got %q", content)
+	}
+	if strings.Join(strings.Fields(content), " ") != "Deals on shoes" {
+		t.Errorf("expected content to keep the text, got %q", content)
+	}
+}
+
+func TestPostBody(t *testing.T) {
+	description := sql.NullString{String: "summary", Valid: true}
+	tests := []struct {
+		name    string
+		content sql.NullString
+		want    string
+	}{
+		{"full text fetched", sql.NullString{String: "full article", Valid: true}, "full article"},
+		{"no full text", sql.NullString{}, "summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postBody(description, tt.content); got != tt.want {
+				t.Errorf("postBody = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFilterRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		params filterRuleParameters
+		wantOK bool
+	}{
+		{"valid keyword", filterRuleParameters{Field: "title", MatchType: "keyword", Pattern: "crypto"}, true},
+		{"valid regex", filterRuleParameters{Field: "content", MatchType: "regex", Pattern: `\bnft\b`}, true},
+		{"unknown field", filterRuleParameters{Field: "url", MatchType: "keyword", Pattern: "crypto"}, false},
+		{"unknown match type", filterRuleParameters{Field: "title", MatchType: "glob", Pattern: "crypto*"}, false},
+		{"blank pattern", filterRuleParameters{Field: "title", MatchType: "keyword", Pattern: "   "}, false},
+		{"invalid regex", filterRuleParameters{Field: "title", MatchType: "regex", Pattern: "[a-"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			matches, ok := validateFilterRule(w, tt.params)
+			if ok != tt.wantOK {
+				t.Fatalf("validateFilterRule ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && matches == nil {
+				t.Error("expected a matcher for a valid rule")
+			}
+			if !ok && w.Code != 400 {
+				t.Errorf("status = %v, want 400", w.Code)
+			}
+		})
+	}
+}
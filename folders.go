@@ -0,0 +1,265 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Used in databaseFolderToFolder(). FeedFollowIDs are the follows filed in the folder
+type Folder struct {
+	ID            uuid.UUID   `json:"id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	Name          string      `json:"name"`
+	FeedFollowIDs []uuid.UUID `json:"feed_follow_ids"`
+}
+
+// Creates a folder for the authenticated user
+func (cfg *apiConfig) handlerFoldersPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if params.Name == "" {
+		respondWithError(w, 400, "Name cannot be empty")
+		return
+	}
+	if !cfg.folderNameAvailable(w, r, user.ID, params.Name, uuid.Nil) {
+		return
+	}
+
+	folder, err := cfg.DB.CreateFolder(r.Context(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      params.Name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating folder: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 201, databaseFolderToFolder(folder, nil))
+}
+
+// Lists the authenticated user's folders & the follows in each
+func (cfg *apiConfig) handlerFoldersGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	folders, err := cfg.DB.GetFoldersByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	assignments, err := cfg.DB.GetFolderAssignmentsByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	byFolder := map[uuid.UUID][]uuid.UUID{}
+	for _, assignment := range assignments {
+		byFolder[assignment.FolderID] = append(byFolder[assignment.FolderID], assignment.FeedFollowID)
+	}
+	payload := []Folder{}
+	for _, folder := range folders {
+		payload = append(payload, databaseFolderToFolder(folder, byFolder[folder.ID]))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Renames a folder
+func (cfg *apiConfig) handlerFoldersPatch(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FolderID")
+		return
+	}
+	type parameters struct {
+		Name string `json:"name"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if params.Name == "" {
+		respondWithError(w, 400, "Name cannot be empty")
+		return
+	}
+	if !cfg.folderNameAvailable(w, r, user.ID, params.Name, folderID) {
+		return
+	}
+
+	folder, err := cfg.DB.RenameFolder(r.Context(), database.RenameFolderParams{
+		ID:     folderID,
+		UserID: user.ID,
+		Name:   params.Name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Folder not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error renaming folder: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	assignments, err := cfg.DB.GetFolderAssignmentsByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	feedFollowIDs := []uuid.UUID{}
+	for _, assignment := range assignments {
+		if assignment.FolderID == folder.ID {
+			feedFollowIDs = append(feedFollowIDs, assignment.FeedFollowID)
+		}
+	}
+	respondWithJSON(w, 200, databaseFolderToFolder(folder, feedFollowIDs))
+}
+
+// Deletes a folder. The follows in it are kept
+func (cfg *apiConfig) handlerFoldersDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FolderID")
+		return
+	}
+	deleted, err := cfg.DB.DeleteFolder(r.Context(), database.DeleteFolderParams{
+		ID:     folderID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Error deleting folder: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Folder not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Files a feed follow in a folder. A follow can be in any number of folders
+func (cfg *apiConfig) handlerFolderFeedFollowPut(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, followID, ok := parseFolderFeedFollowIDs(w, r)
+	if !ok {
+		return
+	}
+	added, err := cfg.DB.AddFeedFollowToFolder(r.Context(), database.AddFeedFollowToFolderParams{
+		FeedFollowID: followID,
+		UserID:       user.ID,
+		FolderID:     folderID,
+	})
+	if err != nil {
+		log.Printf("Error adding feed follow to folder: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if added == 0 {
+		respondWithError(w, 404, "Folder or feed follow not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Removes a feed follow from a folder, without unfollowing the feed
+func (cfg *apiConfig) handlerFolderFeedFollowDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	folderID, followID, ok := parseFolderFeedFollowIDs(w, r)
+	if !ok {
+		return
+	}
+	removed, err := cfg.DB.RemoveFeedFollowFromFolder(r.Context(), database.RemoveFeedFollowFromFolderParams{
+		FeedFollowID: followID,
+		FolderID:     folderID,
+		UserID:       user.ID,
+	})
+	if err != nil {
+		log.Printf("Error removing feed follow from folder: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, 404, "Feed follow is not in this folder")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Parses the folder & feed follow IDs in /folders/{id}/feed_follows/{feed_follow_id}
+func parseFolderFeedFollowIDs(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	folderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FolderID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	followID, err := uuid.Parse(chi.URLParam(r, "feed_follow_id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FeedFollowID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return folderID, followID, true
+}
+
+// Responds with 409 & returns false if the user already has another folder with this name
+func (cfg *apiConfig) folderNameAvailable(w http.ResponseWriter, r *http.Request, userID uuid.UUID, name string, folderID uuid.UUID) bool {
+	existing, err := cfg.DB.GetFolderByName(r.Context(), database.GetFolderByNameParams{
+		UserID: userID,
+		Name:   name,
+	})
+	if err == nil && existing.ID != folderID {
+		respondWithError(w, 409, "A folder with this name already exists")
+		return false
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 500, "Internal server error")
+		return false
+	}
+	return true
+}
+
+// Parses the optional ?folder_id= filter
+func parseFolderFilter(r *http.Request) (uuid.NullUUID, error) {
+	folderStr := r.URL.Query().Get("folder_id")
+	if folderStr == "" {
+		return uuid.NullUUID{}, nil
+	}
+	folderID, err := uuid.Parse(folderStr)
+	if err != nil {
+		return uuid.NullUUID{}, err
+	}
+	return uuid.NullUUID{UUID: folderID, Valid: true}, nil
+}
+
+// Helper func that converts database.Folder to Folder
+func databaseFolderToFolder(dbFolder database.Folder, feedFollowIDs []uuid.UUID) Folder {
+	if feedFollowIDs == nil {
+		feedFollowIDs = []uuid.UUID{}
+	}
+	return Folder{
+		ID:            dbFolder.ID,
+		CreatedAt:     dbFolder.CreatedAt,
+		UpdatedAt:     dbFolder.UpdatedAt,
+		Name:          dbFolder.Name,
+		FeedFollowIDs: feedFollowIDs,
+	}
+}
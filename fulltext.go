@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Largest article page the worker will download, in bytes
+const maxArticleSize = 5 << 20
+
+// Client used for downloading article pages. Has a timeout so a slow site can't stall the worker,
+// & only connects to public addresses since the links come from feeds
+var articleClient = newPublicClient(20 * time.Second)
+
+// Class/id patterns used to weigh candidate nodes, based on Mozilla's Readability
+var (
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
+	maybeCandidate     = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveWeight     = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negativeWeight     = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+// Elements that never contain article content and are dropped before scoring
+var strippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Input:    true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Nav:      true,
+	atom.Aside:    true,
+	atom.Footer:   true,
+	atom.Svg:      true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Link:     true,
+	atom.Meta:     true,
+}
+
+// Attributes kept on extracted content, everything else (style, on*, class...) is removed
+var allowedAttributes = map[string]bool{
+	"href":    true,
+	"src":     true,
+	"alt":     true,
+	"title":   true,
+	"width":   true,
+	"height":  true,
+	"colspan": true,
+	"rowspan": true,
+}
+
+// Downloads a post's linked article & stores the extracted body as the post's content, which is also returned
+func (cfg *apiConfig) fetchFullText(ctx context.Context, postID uuid.UUID, link string) (string, error) {
+	articleURL, err := url.Parse(link)
+	if err != nil || (articleURL.Scheme != "http" && articleURL.Scheme != "https") {
+		return "", fmt.Errorf("invalid article url: %v", link)
+	}
+
+	// A per-site selector override takes precedence over the heuristic extractor
+	selector := ""
+	rule, err := cfg.DB.GetExtractionRuleByHost(ctx, normalizeHost(articleURL.Host))
+	if err == nil {
+		selector = rule.Selector
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("get extraction rule: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, articleURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %v", err)
+	}
+	resp, err := articleClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status error: %v", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("content-type"); !strings.Contains(contentType, "html") {
+		return "", fmt.Errorf("invalid response 'content-type': %v", contentType)
+	}
+
+	if resp.ContentLength > maxArticleSize {
+		return "", fmt.Errorf("article too large: %v bytes", resp.ContentLength)
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxArticleSize+1))
+	if err != nil {
+		return "", fmt.Errorf("read body: %v", err)
+	}
+	if len(page) > maxArticleSize {
+		return "", errors.New("article too large")
+	}
+
+	content, err := extractArticle(bytes.NewReader(page), selector)
+	if err != nil {
+		return "", err
+	}
+
+	err = cfg.DB.SetPostContent(ctx, database.SetPostContentParams{
+		ID:      postID,
+		Content: sql.NullString{String: content, Valid: true},
+	})
+	if err != nil {
+		return "", err
+	}
+	return content, nil
+}
+
+// Extracts the main article body from an HTML page. If selector is set, the matching
+// elements are used as the article, otherwise the content is found with readability-style scoring
+func extractArticle(r io.Reader, selector string) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("HTML parse error: %v", err)
+	}
+	removeNodes(doc, func(n *html.Node) bool {
+		return n.Type == html.CommentNode || (n.Type == html.ElementNode && strippedElements[n.DataAtom])
+	})
+
+	var nodes []*html.Node
+	if selector != "" {
+		sel, err := cascadia.Compile(selector)
+		if err != nil {
+			return "", fmt.Errorf("invalid selector %q: %v", selector, err)
+		}
+		nodes = sel.MatchAll(doc)
+	}
+	// Falls back to scoring when there's no override, or the override no longer matches anything
+	if len(nodes) == 0 {
+		article := findArticleNode(doc)
+		if article == nil {
+			return "", errors.New("no article content found")
+		}
+		nodes = []*html.Node{article}
+	}
+
+	var buf bytes.Buffer
+	for _, node := range nodes {
+		cleanAttributes(node)
+		if err := html.Render(&buf, node); err != nil {
+			return "", fmt.Errorf("HTML render error: %v", err)
+		}
+	}
+	return buf.String(), nil
+}
+
+// Scores every paragraph's ancestors & returns the highest scoring node
+func findArticleNode(doc *html.Node) *html.Node {
+	// Drops elements that look like page chrome, unless they also look like content
+	removeNodes(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom == atom.Body || n.DataAtom == atom.Html || n.DataAtom == atom.Article {
+			return false
+		}
+		match := className(n)
+		return unlikelyCandidates.MatchString(match) && !maybeCandidate.MatchString(match)
+	})
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.DataAtom == atom.P || n.DataAtom == atom.Pre || n.DataAtom == atom.Td) {
+			text := strings.TrimSpace(textContent(n))
+			if len(text) >= 25 {
+				// One point for the paragraph, one per comma, and one per 100 characters (up to 3)
+				score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+				addScore(n.Parent, score)
+				if n.Parent != nil {
+					addScore(n.Parent.Parent, score/2)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	var top *html.Node
+	topScore := 0.0
+	for _, candidate := range candidates {
+		// Scales the score by how much of the candidate isn't link text
+		score := scores[candidate] * (1 - linkDensity(candidate))
+		if top == nil || score > topScore {
+			top = candidate
+			topScore = score
+		}
+	}
+	return top
+}
+
+// Starting score of a candidate, based on its tag & class/id names
+func initialScore(n *html.Node) float64 {
+	score := 0.0
+	switch n.DataAtom {
+	case atom.Article:
+		score += 10
+	case atom.Div:
+		score += 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+	for _, attr := range n.Attr {
+		if attr.Key != "class" && attr.Key != "id" {
+			continue
+		}
+		if negativeWeight.MatchString(attr.Val) {
+			score -= 25
+		}
+		if positiveWeight.MatchString(attr.Val) {
+			score += 25
+		}
+	}
+	return score
+}
+
+// Ratio of link text to all text within a node
+func linkDensity(n *html.Node) float64 {
+	textLength := len(textContent(n))
+	if textLength == 0 {
+		return 0
+	}
+	linkLength := 0
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linkLength += len(textContent(c))
+			return
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return float64(linkLength) / float64(textLength)
+}
+
+// Concatenated text of a node & all of its descendants
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+// Class & id of an element, joined for matching against the weight patterns
+func className(n *html.Node) string {
+	var parts []string
+	for _, attr := range n.Attr {
+		if attr.Key == "class" || attr.Key == "id" {
+			parts = append(parts, attr.Val)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Removes every descendant of n that matches the predicate
+func removeNodes(n *html.Node, match func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		if match(c) {
+			n.RemoveChild(c)
+		} else {
+			removeNodes(c, match)
+		}
+		c = next
+	}
+}
+
+// Strips presentational & scripting attributes from a node & its descendants
+func cleanAttributes(n *html.Node) {
+	if n.Type == html.ElementNode {
+		attrs := n.Attr[:0]
+		for _, attr := range n.Attr {
+			if !allowedAttributes[attr.Key] {
+				continue
+			}
+			// Drops "javascript:" links & sources
+			if (attr.Key == "href" || attr.Key == "src") && strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "javascript:") {
+				continue
+			}
+			attrs = append(attrs, attr)
+		}
+		n.Attr = attrs
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		cleanAttributes(c)
+	}
+}
+
+// Lowercases a host & drops its port & "www." prefix, so rules match regardless of how links are written
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	// Hosts without a port are kept as they are, IPv6 ones included
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.TrimPrefix(host, "www.")
+}
+
+// Retrieves all per-site extraction rules
+func (cfg *apiConfig) handlerExtractionRulesGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	rules, err := cfg.DB.GetExtractionRules(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	respondWithJSON(w, 200, rules)
+}
+
+// Creates or replaces the extraction rule for a site
+func (cfg *apiConfig) handlerExtractionRulesPut(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Host     string `json:"host"`
+		Selector string `json:"selector"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	host := normalizeHost(params.Host)
+	if host == "" || params.Selector == "" {
+		respondWithError(w, 400, "Fields cannot be empty")
+		return
+	}
+	if _, err := cascadia.Compile(params.Selector); err != nil {
+		respondWithError(w, 400, "Invalid selector")
+		return
+	}
+
+	rule, err := cfg.DB.UpsertExtractionRule(r.Context(), database.UpsertExtractionRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Host:      host,
+		Selector:  params.Selector,
+	})
+	if err != nil {
+		log.Printf("Error saving extraction rule: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, rule)
+}
+
+// Deletes an extraction rule
+func (cfg *apiConfig) handlerExtractionRulesDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	ruleID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid ExtractionRuleID")
+		return
+	}
+	err = cfg.DB.DeleteExtractionRule(r.Context(), ruleID)
+	if err != nil {
+		log.Printf("Error deleting extraction rule: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Runs extractArticle on a page from testdata/
+func extractFixture(t *testing.T, name string, selector string) string {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open fixture: %v", err)
+	}
+	defer f.Close()
+	content, err := extractArticle(f, selector)
+	if err != nil {
+		t.Fatalf("extractArticle(%v, %q): %v", name, selector, err)
+	}
+	return content
+}
+
+func assertContains(t *testing.T, content string, want ...string) {
+	t.Helper()
+	for _, s := range want {
+		if !strings.Contains(content, s) {
+			t.Errorf("expected %q in extracted content:\n%s", s, content)
+		}
+	}
+}
+
+func assertNotContains(t *testing.T, content string, unwanted ...string) {
+	t.Helper()
+	for _, s := range unwanted {
+		if strings.Contains(content, s) {
+			t.Errorf("didn't expect %q in extracted content:\n%s", s, content)
+		}
+	}
+}
+
+func TestExtractArticleScoring(t *testing.T) {
+	content := extractFixture(t, "article.html", "")
+
+	if !strings.HasPrefix(content, "<div>") {
+		t.Errorf("expected the article's div to be picked, got:\n%s", content)
+	}
+	assertContains(t, content,
+		"Tidal power, explained",
+		"the tides can be predicted years ahead",
+		"much like a hydroelectric dam",
+		"underwater wind turbines",
+		"the first commercial arrays are already running",
+	)
+}
+
+func TestExtractArticleRemovesBoilerplate(t *testing.T) {
+	content := extractFixture(t, "article.html", "")
+
+	assertNotContains(t, content,
+		// Navigation, sidebar, related links, comments & footer
+		"About",
+		"Subscribe to our newsletter",
+		"Wave power",
+		"First comment",
+		"Copyright",
+		// Scripts, styles & comments, wherever they are
+		"<script",
+		"trackPageview",
+		"injected by an ad network",
+		"font-family",
+		"editor: check these figures",
+	)
+}
+
+func TestExtractArticleCleansAttributes(t *testing.T) {
+	content := extractFixture(t, "attributes.html", "")
+
+	assertContains(t, content,
+		`<a href="https://example.com/source">like this one</a>`,
+		`<img src="https://example.com/chart.png" alt="A chart" width="640" height="480"/>`,
+		`<a>click me</a>`,
+		`<img alt="Broken"/>`,
+		`<td colspan="2">`,
+	)
+	assertNotContains(t, content,
+		"style=",
+		"class=",
+		"data-tracking-id",
+		"onclick",
+		"onmouseover",
+		"onerror",
+		"target=",
+		"rel=",
+		"loading=",
+		"bgcolor",
+		"steal()",
+	)
+}
+
+func TestExtractArticleSelectorOverride(t *testing.T) {
+	tests := []struct {
+		name       string
+		selector   string
+		want       []string
+		unwanted   []string
+		wantPrefix string
+	}{
+		{
+			name:     "no override uses scoring",
+			selector: "",
+			want:     []string{"is what the scoring picks"},
+			unwanted: []string{"The real story is short"},
+		},
+		{
+			name:       "override replaces scoring",
+			selector:   "section.story-text",
+			want:       []string{"The real story is short.", "It is split in two parts."},
+			unwanted:   []string{"teaser", "class="},
+			wantPrefix: "<section>",
+		},
+		{
+			name:     "override matching nothing falls back to scoring",
+			selector: "div.article-body",
+			want:     []string{"is what the scoring picks"},
+			unwanted: []string{"The real story is short"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := extractFixture(t, "selector.html", tt.selector)
+			assertContains(t, content, tt.want...)
+			assertNotContains(t, content, tt.unwanted...)
+			if !strings.HasPrefix(content, tt.wantPrefix) {
+				t.Errorf("expected content to start with %q, got:\n%s", tt.wantPrefix, content)
+			}
+		})
+	}
+}
+
+func TestExtractArticleInvalidSelector(t *testing.T) {
+	_, err := extractArticle(strings.NewReader("<p>Some text</p>"), "div[")
+	if err == nil {
+		t.Fatal("expected an error for an invalid selector")
+	}
+}
+
+func TestArticleClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>Internal only</p>"))
+	}))
+	defer server.Close()
+
+	resp, err := articleClient.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("expected the request to %v to be refused", server.URL)
+	}
+	if !strings.Contains(err.Error(), "refusing to connect") {
+		t.Errorf("expected a refused connection, got: %v", err)
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"WWW.Example.com", "example.com"},
+		{"example.com:8080", "example.com"},
+		{"www.example.com:443", "example.com"},
+		{"[::1]:8080", "::1"},
+		{"::1", "::1"},
+		{"[::1]", "::1"},
+		{"2001:db8::1", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := normalizeHost(tt.host); got != tt.want {
+				t.Errorf("normalizeHost(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,8 +5,12 @@ go 1.21.3
 require github.com/joho/godotenv v1.5.1
 
 require (
+	github.com/andybalholm/cascadia v1.3.2
 	github.com/go-chi/chi/v5 v5.0.10
 	github.com/go-chi/cors v1.2.1
 	github.com/google/uuid v1.4.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.17.0
 )
+
+require golang.org/x/crypto v0.14.0
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Limits for proxied images
+const (
+	maxProxiedImageSize = 10 << 20
+	imageCacheSize      = 64 << 20
+	imageCacheTTL       = 24 * time.Hour
+)
+
+// Content types the proxy will serve. SVG is left out on purpose, since it can carry scripts
+var proxiedImageTypes = map[string]bool{
+	"image/jpeg":               true,
+	"image/png":                true,
+	"image/gif":                true,
+	"image/webp":               true,
+	"image/avif":               true,
+	"image/bmp":                true,
+	"image/x-icon":             true,
+	"image/vnd.microsoft.icon": true,
+}
+
+// Client used for fetching proxied images
+var imageProxyClient = newPublicClient(15 * time.Second)
+
+// Builds a client for fetching URLs we don't control. It refuses to connect to private addresses,
+// so signed image URLs & links in feeds can't be used to reach services on our own network
+func newPublicClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy, the dialer check would only see the proxy's address & not the host being fetched
+			Proxy: nil,
+			DialContext: (&net.Dialer{
+				Timeout: 5 * time.Second,
+				Control: refusePrivateAddress,
+			}).DialContext,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// Dialer check that fails for loopback, private & link-local addresses. Runs after DNS resolution,
+// so it also catches public hostnames that resolve to internal addresses
+func refusePrivateAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return fmt.Errorf("refusing to connect to %v", host)
+	}
+	return nil
+}
+
+type cachedImage struct {
+	contentType string
+	data        []byte
+	expiresAt   time.Time
+}
+
+// In-memory cache of proxied images, evicting the oldest entries once maxSize bytes are stored
+type imageCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedImage
+	order   []string
+	size    int
+	maxSize int
+}
+
+func newImageCache(maxSize int) *imageCache {
+	return &imageCache{
+		entries: map[string]cachedImage{},
+		maxSize: maxSize,
+	}
+}
+
+func (c *imageCache) get(key string) (cachedImage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img, ok := c.entries[key]
+	if !ok {
+		return cachedImage{}, false
+	}
+	// Expired entries are dropped here, eviction only happens once the cache is full
+	if time.Now().After(img.expiresAt) {
+		c.remove(key)
+		return cachedImage{}, false
+	}
+	return img, true
+}
+
+// Drops an entry, the caller holds c.mu
+func (c *imageCache) remove(key string) {
+	c.size -= len(c.entries[key].data)
+	delete(c.entries, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (c *imageCache) add(key string, img cachedImage) {
+	if len(img.data) > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.entries[key]; ok {
+		c.size -= len(old.data)
+	} else {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = img
+	c.size += len(img.data)
+
+	for c.size > c.maxSize && len(c.order) > 0 {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		c.size -= len(c.entries[oldest].data)
+		delete(c.entries, oldest)
+	}
+}
+
+// Signs an image URL with the proxy key
+func (cfg *apiConfig) signImageURL(imageURL string) string {
+	mac := hmac.New(sha256.New, cfg.ImageProxyKey)
+	mac.Write([]byte(imageURL))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Builds the proxy URL for an image
+func (cfg *apiConfig) imageProxyURL(imageURL string) string {
+	return fmt.Sprintf("%s/imageproxy/%s/%s", cfg.PublicURL, cfg.signImageURL(imageURL), base64.RawURLEncoding.EncodeToString([]byte(imageURL)))
+}
+
+// Rewrites every <img src> in a post's HTML to go through the image proxy.
+// Relative sources are resolved against the post's URL
+func (cfg *apiConfig) proxyImages(content string, postURL string) string {
+	if !strings.Contains(strings.ToLower(content), "<img") {
+		return content
+	}
+	base, _ := url.Parse(postURL)
+
+	var buf bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if errors.Is(z.Err(), io.EOF) {
+				return buf.String()
+			}
+			// Not worth serving half-rewritten content, so drop the images altogether
+			return ""
+		}
+		raw := z.Raw()
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			buf.Write(raw)
+			continue
+		}
+		token := z.Token()
+		if token.DataAtom != atom.Img {
+			buf.Write(raw)
+			continue
+		}
+
+		attrs := token.Attr[:0]
+		for _, attr := range token.Attr {
+			switch attr.Key {
+			case "srcset":
+				// Can't be signed as a whole & src is enough of a fallback
+				continue
+			case "src":
+				src, err := url.Parse(strings.TrimSpace(attr.Val))
+				if err != nil {
+					continue
+				}
+				if base != nil {
+					src = base.ResolveReference(src)
+				}
+				if src.Scheme != "http" && src.Scheme != "https" {
+					continue
+				}
+				attr.Val = cfg.imageProxyURL(src.String())
+			}
+			attrs = append(attrs, attr)
+		}
+		token.Attr = attrs
+		buf.WriteString(token.String())
+	}
+}
+
+// Fetches & serves an image from a signed proxy URL
+func (cfg *apiConfig) handlerImageProxy(w http.ResponseWriter, r *http.Request) {
+	signature := chi.URLParam(r, "signature")
+	decoded, err := base64.RawURLEncoding.DecodeString(chi.URLParam(r, "url"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid image URL")
+		return
+	}
+	imageURL := string(decoded)
+	if !hmac.Equal([]byte(signature), []byte(cfg.signImageURL(imageURL))) {
+		respondWithError(w, 403, "Invalid signature")
+		return
+	}
+
+	// Proxied URLs never change, so the signature doubles as an ETag
+	etag := `"` + signature + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	img, ok := cfg.ImageCache.get(signature)
+	if !ok {
+		img, err = fetchProxiedImage(r.Context(), imageURL)
+		if err != nil {
+			fmt.Printf("Error proxying %v: %v\n", imageURL, err)
+			respondWithError(w, 502, "Could not fetch image")
+			return
+		}
+		cfg.ImageCache.add(signature, img)
+	}
+
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(img.data)))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(imageCacheTTL.Seconds())))
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+	w.WriteHeader(200)
+	w.Write(img.data)
+}
+
+// Downloads an image, enforcing the proxy's type & size limits
+func fetchProxiedImage(ctx context.Context, imageURL string) (cachedImage, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return cachedImage{}, fmt.Errorf("invalid image url")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return cachedImage{}, fmt.Errorf("build request: %v", err)
+	}
+	resp, err := imageProxyClient.Do(req)
+	if err != nil {
+		return cachedImage{}, fmt.Errorf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return cachedImage{}, fmt.Errorf("status error: %v", resp.StatusCode)
+	}
+	contentType, _, _ := strings.Cut(resp.Header.Get("content-type"), ";")
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if !proxiedImageTypes[contentType] {
+		return cachedImage{}, fmt.Errorf("invalid response 'content-type': %v", contentType)
+	}
+	if resp.ContentLength > maxProxiedImageSize {
+		return cachedImage{}, fmt.Errorf("image too large: %v bytes", resp.ContentLength)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProxiedImageSize+1))
+	if err != nil {
+		return cachedImage{}, fmt.Errorf("read body: %v", err)
+	}
+	if len(data) > maxProxiedImageSize {
+		return cachedImage{}, fmt.Errorf("image too large")
+	}
+	// Double-checks the type against the actual bytes, in case the server lied
+	if sniffed := http.DetectContentType(data); strings.HasPrefix(sniffed, "text/") {
+		return cachedImage{}, fmt.Errorf("response is not an image: %v", sniffed)
+	}
+
+	return cachedImage{
+		contentType: contentType,
+		data:        data,
+		expiresAt:   time.Now().Add(imageCacheTTL),
+	}, nil
+}
@@ -7,15 +7,28 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countFollowedFeeds = `-- name: CountFollowedFeeds :one
+SELECT count(*) FROM feed_follows
+WHERE user_id = $1
+`
+
+func (q *Queries) CountFollowedFeeds(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFollowedFeeds, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const followFeed = `-- name: FollowFeed :one
 INSERT INTO feed_follows (id, user_id, feed_id, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, feed_id, created_at, updated_at
+RETURNING id, user_id, feed_id, created_at, updated_at, title, notes, priority, show_in_timeline
 `
 
 type FollowFeedParams struct {
@@ -41,13 +54,107 @@ func (q *Queries) FollowFeed(ctx context.Context, arg FollowFeedParams) (FeedFol
 		&i.FeedID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
+		&i.ShowInTimeline,
+	)
+	return i, err
+}
+
+const getFeedFollowByFeedID = `-- name: GetFeedFollowByFeedID :one
+SELECT id, user_id, feed_id, created_at, updated_at, title, notes, priority, show_in_timeline FROM feed_follows
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type GetFeedFollowByFeedIDParams struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetFeedFollowByFeedID(ctx context.Context, arg GetFeedFollowByFeedIDParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowByFeedID, arg.FeedID, arg.UserID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
+		&i.ShowInTimeline,
+	)
+	return i, err
+}
+
+const getFeedFollowByID = `-- name: GetFeedFollowByID :one
+SELECT id, user_id, feed_id, created_at, updated_at, title, notes, priority, show_in_timeline FROM feed_follows
+WHERE id = $1 AND user_id = $2
+`
+
+type GetFeedFollowByIDParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetFeedFollowByID(ctx context.Context, arg GetFeedFollowByIDParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowByID, arg.ID, arg.UserID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
+		&i.ShowInTimeline,
 	)
 	return i, err
 }
 
+const getFeedTitles = `-- name: GetFeedTitles :many
+SELECT feed_follows.feed_id, COALESCE(feed_follows.title, feeds.name)::text AS feed_title
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+`
+
+type GetFeedTitlesRow struct {
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedTitle string    `json:"feed_title"`
+}
+
+func (q *Queries) GetFeedTitles(ctx context.Context, userID uuid.UUID) ([]GetFeedTitlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedTitles, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedTitlesRow
+	for rows.Next() {
+		var i GetFeedTitlesRow
+		if err := rows.Scan(&i.FeedID, &i.FeedTitle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFollowedFeeds = `-- name: GetFollowedFeeds :many
-SELECT id, user_id, feed_id, created_at, updated_at FROM feed_follows
+SELECT id, user_id, feed_id, created_at, updated_at, title, notes, priority, show_in_timeline FROM feed_follows
 WHERE user_id = $1
+ORDER BY priority DESC, created_at
 `
 
 func (q *Queries) GetFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error) {
@@ -65,6 +172,112 @@ func (q *Queries) GetFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]Fee
 			&i.FeedID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Title,
+			&i.Notes,
+			&i.Priority,
+			&i.ShowInTimeline,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFollowedFeedsInFolder = `-- name: GetFollowedFeedsInFolder :many
+SELECT feed_follows.id, feed_follows.user_id, feed_follows.feed_id, feed_follows.created_at, feed_follows.updated_at, feed_follows.title, feed_follows.notes, feed_follows.priority, feed_follows.show_in_timeline FROM feed_follows
+JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = $1 AND feed_follow_folders.folder_id = $2
+ORDER BY feed_follows.priority DESC, feed_follows.created_at
+`
+
+type GetFollowedFeedsInFolderParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	FolderID uuid.UUID `json:"folder_id"`
+}
+
+func (q *Queries) GetFollowedFeedsInFolder(ctx context.Context, arg GetFollowedFeedsInFolderParams) ([]FeedFollow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsInFolder, arg.UserID, arg.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedFollow
+	for rows.Next() {
+		var i FeedFollow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FeedID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Notes,
+			&i.Priority,
+			&i.ShowInTimeline,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFollowedFeedsWithDetails = `-- name: GetFollowedFeedsWithDetails :many
+SELECT feed_follows.id, feed_follows.user_id, feed_follows.feed_id, feed_follows.created_at, feed_follows.updated_at, feed_follows.title, feed_follows.notes, feed_follows.priority, feed_follows.show_in_timeline, feeds.name AS feed_name, feeds.url AS feed_url
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at
+`
+
+type GetFollowedFeedsWithDetailsRow struct {
+	ID             uuid.UUID      `json:"id"`
+	UserID         uuid.UUID      `json:"user_id"`
+	FeedID         uuid.UUID      `json:"feed_id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Title          sql.NullString `json:"title"`
+	Notes          string         `json:"notes"`
+	Priority       int32          `json:"priority"`
+	ShowInTimeline bool           `json:"show_in_timeline"`
+	FeedName       string         `json:"feed_name"`
+	FeedUrl        string         `json:"feed_url"`
+}
+
+func (q *Queries) GetFollowedFeedsWithDetails(ctx context.Context, userID uuid.UUID) ([]GetFollowedFeedsWithDetailsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsWithDetails, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsWithDetailsRow
+	for rows.Next() {
+		var i GetFollowedFeedsWithDetailsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FeedID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Notes,
+			&i.Priority,
+			&i.ShowInTimeline,
+			&i.FeedName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -93,3 +306,44 @@ func (q *Queries) UnfollowFeed(ctx context.Context, arg UnfollowFeedParams) erro
 	_, err := q.db.ExecContext(ctx, unfollowFeed, arg.ID, arg.UserID)
 	return err
 }
+
+const updateFeedFollow = `-- name: UpdateFeedFollow :one
+UPDATE feed_follows
+SET title = $1::text, notes = $2, priority = $3,
+    show_in_timeline = $4, updated_at = LOCALTIMESTAMP
+WHERE id = $5 AND user_id = $6
+RETURNING id, user_id, feed_id, created_at, updated_at, title, notes, priority, show_in_timeline
+`
+
+type UpdateFeedFollowParams struct {
+	Title          sql.NullString `json:"title"`
+	Notes          string         `json:"notes"`
+	Priority       int32          `json:"priority"`
+	ShowInTimeline bool           `json:"show_in_timeline"`
+	ID             uuid.UUID      `json:"id"`
+	UserID         uuid.UUID      `json:"user_id"`
+}
+
+func (q *Queries) UpdateFeedFollow(ctx context.Context, arg UpdateFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollow,
+		arg.Title,
+		arg.Notes,
+		arg.Priority,
+		arg.ShowInTimeline,
+		arg.ID,
+		arg.UserID,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FeedID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Notes,
+		&i.Priority,
+		&i.ShowInTimeline,
+	)
+	return i, err
+}
@@ -7,24 +7,26 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fetch_full_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant
 `
 
 type CreateFeedParams struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Name      string    `json:"name"`
-	Url       string    `json:"url"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID            uuid.UUID     `json:"id"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+	Name          string        `json:"name"`
+	Url           string        `json:"url"`
+	UserID        uuid.NullUUID `json:"user_id"`
+	FetchFullText bool          `json:"fetch_full_text"`
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -35,6 +37,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.FetchFullText,
 	)
 	var i Feed
 	err := row.Scan(
@@ -45,12 +48,48 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.RetentionKeep,
+		&i.RetentionMaxAgeDays,
+		&i.LastPostAt,
+		&i.Dormant,
 	)
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedFeeds = `-- name: DeleteOrphanedFeeds :execrows
+DELETE FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM starred_posts
+    JOIN posts ON posts.id = starred_posts.post_id
+    WHERE posts.feed_id = feeds.id
+)
+`
+
+func (q *Queries) DeleteOrphanedFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOrphanedFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getAllFeeds = `-- name: GetAllFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant FROM feeds
 `
 
 func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
@@ -70,6 +109,78 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.FetchFullText,
+			&i.RetentionKeep,
+			&i.RetentionMaxAgeDays,
+			&i.LastPostAt,
+			&i.Dormant,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.RetentionKeep,
+		&i.RetentionMaxAgeDays,
+		&i.LastPostAt,
+		&i.Dormant,
+	)
+	return i, err
+}
+
+const getFeedsByCreator = `-- name: GetFeedsByCreator :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant FROM feeds
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetFeedsByCreator(ctx context.Context, userID uuid.NullUUID) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsByCreator, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullText,
+			&i.RetentionKeep,
+			&i.RetentionMaxAgeDays,
+			&i.LastPostAt,
+			&i.Dormant,
 		); err != nil {
 			return nil, err
 		}
@@ -85,13 +196,20 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 }
 
 const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND (NOT dormant OR last_fetched_at IS NULL OR last_fetched_at < $1::timestamp)
 ORDER BY last_fetched_at NULLS FIRST
-LIMIT $1
+LIMIT $2
 `
 
-func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed, error) {
-	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, limit)
+type GetNextFeedsToFetchParams struct {
+	DormantFetchedBefore time.Time `json:"dormant_fetched_before"`
+	LimitCount           int32     `json:"limit_count"`
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch, arg.DormantFetchedBefore, arg.LimitCount)
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +225,76 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.FetchFullText,
+			&i.RetentionKeep,
+			&i.RetentionMaxAgeDays,
+			&i.LastPostAt,
+			&i.Dormant,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrphanedFeeds = `-- name: GetOrphanedFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.fetch_full_text, feeds.retention_keep, feeds.retention_max_age_days, feeds.last_post_at, feeds.dormant, (SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id)::bigint AS post_count
+FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM starred_posts
+    JOIN posts ON posts.id = starred_posts.post_id
+    WHERE posts.feed_id = feeds.id
+)
+ORDER BY created_at
+`
+
+type GetOrphanedFeedsRow struct {
+	ID                  uuid.UUID     `json:"id"`
+	CreatedAt           time.Time     `json:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+	Name                string        `json:"name"`
+	Url                 string        `json:"url"`
+	UserID              uuid.NullUUID `json:"user_id"`
+	LastFetchedAt       sql.NullTime  `json:"last_fetched_at"`
+	FetchFullText       bool          `json:"fetch_full_text"`
+	RetentionKeep       sql.NullInt32 `json:"retention_keep"`
+	RetentionMaxAgeDays sql.NullInt32 `json:"retention_max_age_days"`
+	LastPostAt          sql.NullTime  `json:"last_post_at"`
+	Dormant             bool          `json:"dormant"`
+	PostCount           int64         `json:"post_count"`
+}
+
+func (q *Queries) GetOrphanedFeeds(ctx context.Context) ([]GetOrphanedFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOrphanedFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOrphanedFeedsRow
+	for rows.Next() {
+		var i GetOrphanedFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.FetchFullText,
+			&i.RetentionKeep,
+			&i.RetentionMaxAgeDays,
+			&i.LastPostAt,
+			&i.Dormant,
+			&i.PostCount,
 		); err != nil {
 			return nil, err
 		}
@@ -121,11 +309,25 @@ func (q *Queries) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]Feed,
 	return items, nil
 }
 
+const markDormantFeeds = `-- name: MarkDormantFeeds :execrows
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, dormant = true
+WHERE NOT dormant AND COALESCE(last_post_at, created_at) < $1::timestamp
+`
+
+func (q *Queries) MarkDormantFeeds(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markDormantFeeds, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const markFeedFetched = `-- name: MarkFeedFetched :one
 UPDATE feeds
 SET updated_at = LOCALTIMESTAMP, last_fetched_at = LOCALTIMESTAMP
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant
 `
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -139,6 +341,103 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.RetentionKeep,
+		&i.RetentionMaxAgeDays,
+		&i.LastPostAt,
+		&i.Dormant,
+	)
+	return i, err
+}
+
+const markFeedHasNewPosts = `-- name: MarkFeedHasNewPosts :exec
+UPDATE feeds
+SET last_post_at = LOCALTIMESTAMP, dormant = false
+WHERE id = $1
+`
+
+func (q *Queries) MarkFeedHasNewPosts(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markFeedHasNewPosts, id)
+	return err
+}
+
+const reassignUserFeeds = `-- name: ReassignUserFeeds :exec
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> feeds.user_id
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+)
+WHERE feeds.user_id = $1
+`
+
+func (q *Queries) ReassignUserFeeds(ctx context.Context, userID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, reassignUserFeeds, userID)
+	return err
+}
+
+const setFeedFetchFullText = `-- name: SetFeedFetchFullText :one
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, fetch_full_text = $2
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant
+`
+
+type SetFeedFetchFullTextParams struct {
+	ID            uuid.UUID `json:"id"`
+	FetchFullText bool      `json:"fetch_full_text"`
+}
+
+func (q *Queries) SetFeedFetchFullText(ctx context.Context, arg SetFeedFetchFullTextParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFetchFullText, arg.ID, arg.FetchFullText)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.RetentionKeep,
+		&i.RetentionMaxAgeDays,
+		&i.LastPostAt,
+		&i.Dormant,
+	)
+	return i, err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, retention_keep = $2, retention_max_age_days = $3
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, fetch_full_text, retention_keep, retention_max_age_days, last_post_at, dormant
+`
+
+type SetFeedRetentionParams struct {
+	ID                  uuid.UUID     `json:"id"`
+	RetentionKeep       sql.NullInt32 `json:"retention_keep"`
+	RetentionMaxAgeDays sql.NullInt32 `json:"retention_max_age_days"`
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention, arg.ID, arg.RetentionKeep, arg.RetentionMaxAgeDays)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.FetchFullText,
+		&i.RetentionKeep,
+		&i.RetentionMaxAgeDays,
+		&i.LastPostAt,
+		&i.Dormant,
 	)
 	return i, err
 }
@@ -6,44 +6,207 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ApiKey struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	KeyHash    string       `json:"key_hash"`
+	KeyPrefix  string       `json:"key_prefix"`
+	Scopes     []string     `json:"scopes"`
+}
+
+type ExportJob struct {
+	ID          uuid.UUID      `json:"id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Status      string         `json:"status"`
+	Data        []byte         `json:"data"`
+	Error       sql.NullString `json:"error"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+}
+
+type ExtractionRule struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Host      string    `json:"host"`
+	Selector  string    `json:"selector"`
+}
+
 type Feed struct {
-	ID            uuid.UUID    `json:"id"`
-	CreatedAt     time.Time    `json:"created_at"`
-	UpdatedAt     time.Time    `json:"updated_at"`
-	Name          string       `json:"name"`
-	Url           string       `json:"url"`
-	UserID        uuid.UUID    `json:"user_id"`
-	LastFetchedAt sql.NullTime `json:"last_fetched_at"`
+	ID                  uuid.UUID     `json:"id"`
+	CreatedAt           time.Time     `json:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+	Name                string        `json:"name"`
+	Url                 string        `json:"url"`
+	UserID              uuid.NullUUID `json:"user_id"`
+	LastFetchedAt       sql.NullTime  `json:"last_fetched_at"`
+	FetchFullText       bool          `json:"fetch_full_text"`
+	RetentionKeep       sql.NullInt32 `json:"retention_keep"`
+	RetentionMaxAgeDays sql.NullInt32 `json:"retention_max_age_days"`
+	LastPostAt          sql.NullTime  `json:"last_post_at"`
+	Dormant             bool          `json:"dormant"`
 }
 
 type FeedFollow struct {
+	ID             uuid.UUID      `json:"id"`
+	UserID         uuid.UUID      `json:"user_id"`
+	FeedID         uuid.UUID      `json:"feed_id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Title          sql.NullString `json:"title"`
+	Notes          string         `json:"notes"`
+	Priority       int32          `json:"priority"`
+	ShowInTimeline bool           `json:"show_in_timeline"`
+}
+
+type FeedFollowFolder struct {
+	FeedFollowID uuid.UUID `json:"feed_follow_id"`
+	FolderID     uuid.UUID `json:"folder_id"`
+}
+
+type FilterRule struct {
+	ID        uuid.UUID      `json:"id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Field     string         `json:"field"`
+	MatchType string         `json:"match_type"`
+	Pattern   string         `json:"pattern"`
+	Action    string         `json:"action"`
+	Tag       sql.NullString `json:"tag"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type Folder struct {
 	ID        uuid.UUID `json:"id"`
 	UserID    uuid.UUID `json:"user_id"`
-	FeedID    uuid.UUID `json:"feed_id"`
+	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type HiddenPost struct {
+	RuleID    uuid.UUID `json:"rule_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InviteCode struct {
+	ID        uuid.UUID     `json:"id"`
+	Code      string        `json:"code"`
+	CreatedBy uuid.NullUUID `json:"created_by"`
+	MaxUses   int32         `json:"max_uses"`
+	Uses      int32         `json:"uses"`
+	ExpiresAt sql.NullTime  `json:"expires_at"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
 type Post struct {
-	ID          uuid.UUID      `json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	Title       string         `json:"title"`
-	Url         string         `json:"url"`
-	Description sql.NullString `json:"description"`
-	PublishedAt sql.NullTime   `json:"published_at"`
-	FeedID      uuid.UUID      `json:"feed_id"`
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Title        string         `json:"title"`
+	Url          string         `json:"url"`
+	Description  sql.NullString `json:"description"`
+	PublishedAt  sql.NullTime   `json:"published_at"`
+	FeedID       uuid.UUID      `json:"feed_id"`
+	Content      sql.NullString `json:"content"`
+	CanonicalUrl string         `json:"canonical_url"`
+	Fingerprint  sql.NullInt64  `json:"fingerprint"`
+	ClusterID    uuid.UUID      `json:"cluster_id"`
+	SearchConfig string         `json:"search_config"`
+	SearchVector interface{}    `json:"search_vector"`
+	Author       sql.NullString `json:"author"`
 }
 
-type User struct {
-	ID        uuid.UUID `json:"id"`
+type PostTag struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	Tag       string    `json:"tag"`
 	CreatedAt time.Time `json:"created_at"`
+}
+
+type PrunedPost struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	Url        string    `json:"url"`
+	PrunedAt   time.Time `json:"pruned_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReadWatermark struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FeedID     uuid.UUID `json:"feed_id"`
+	ReadBefore time.Time `json:"read_before"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type SavedSearch struct {
+	ID        uuid.UUID      `json:"id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Name      string         `json:"name"`
+	Query     string         `json:"query"`
+	FeedID    uuid.NullUUID  `json:"feed_id"`
+	FolderID  uuid.NullUUID  `json:"folder_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Since     sql.NullTime   `json:"since"`
+	Until     sql.NullTime   `json:"until"`
+	Starred   sql.NullBool   `json:"starred"`
+	Tag       sql.NullString `json:"tag"`
+	Sort      string         `json:"sort"`
+}
+
+type Session struct {
+	ID               uuid.UUID     `json:"id"`
+	UserID           uuid.UUID     `json:"user_id"`
+	AccessTokenHash  string        `json:"access_token_hash"`
+	AccessExpiresAt  time.Time     `json:"access_expires_at"`
+	RefreshTokenHash string        `json:"refresh_token_hash"`
+	RefreshExpiresAt time.Time     `json:"refresh_expires_at"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+	ImpersonatedBy   uuid.NullUUID `json:"impersonated_by"`
+}
+
+type StarredPost struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type User struct {
+	ID           uuid.UUID       `json:"id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	Name         string          `json:"name"`
+	Email        sql.NullString  `json:"email"`
+	PasswordHash sql.NullString  `json:"password_hash"`
+	DisabledAt   sql.NullTime    `json:"disabled_at"`
+	IsAdmin      bool            `json:"is_admin"`
+	Preferences  json.RawMessage `json:"preferences"`
+}
+
+type UserPostState struct {
+	UserID    uuid.UUID `json:"user_id"`
+	PostID    uuid.UUID `json:"post_id"`
+	IsRead    bool      `json:"is_read"`
 	UpdatedAt time.Time `json:"updated_at"`
-	Name      string    `json:"name"`
-	ApiKey    string    `json:"api_key"`
 }
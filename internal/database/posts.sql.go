@@ -8,22 +8,29 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const addPost = `-- name: AddPost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, LOCALTIMESTAMP, LOCALTIMESTAMP, $2, $3, $4, $5, $6)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, canonical_url, fingerprint, cluster_id, search_config, author)
+VALUES ($1, LOCALTIMESTAMP, LOCALTIMESTAMP, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 `
 
 type AddPostParams struct {
-	ID          uuid.UUID      `json:"id"`
-	Title       string         `json:"title"`
-	Url         string         `json:"url"`
-	Description sql.NullString `json:"description"`
-	PublishedAt sql.NullTime   `json:"published_at"`
-	FeedID      uuid.UUID      `json:"feed_id"`
+	ID           uuid.UUID      `json:"id"`
+	Title        string         `json:"title"`
+	Url          string         `json:"url"`
+	Description  sql.NullString `json:"description"`
+	PublishedAt  sql.NullTime   `json:"published_at"`
+	FeedID       uuid.UUID      `json:"feed_id"`
+	CanonicalUrl string         `json:"canonical_url"`
+	Fingerprint  sql.NullInt64  `json:"fingerprint"`
+	ClusterID    uuid.UUID      `json:"cluster_id"`
+	SearchConfig string         `json:"search_config"`
+	Author       sql.NullString `json:"author"`
 }
 
 func (q *Queries) AddPost(ctx context.Context, arg AddPostParams) error {
@@ -34,27 +41,198 @@ func (q *Queries) AddPost(ctx context.Context, arg AddPostParams) error {
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.CanonicalUrl,
+		arg.Fingerprint,
+		arg.ClusterID,
+		arg.SearchConfig,
+		arg.Author,
 	)
 	return err
 }
 
+const deleteFeedPostsBeyondLimit = `-- name: DeleteFeedPostsBeyondLimit :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.feed_id = $1 AND posts.id NOT IN (
+        SELECT kept.id FROM posts kept
+        WHERE kept.feed_id = $1
+        ORDER BY COALESCE(kept.published_at, kept.created_at) DESC, kept.id DESC
+        LIMIT $2::int
+    )
+    AND NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+    RETURNING posts.feed_id, posts.url
+)
+INSERT INTO pruned_posts (feed_id, url, pruned_at, last_seen_at)
+SELECT deleted.feed_id, deleted.url, LOCALTIMESTAMP, LOCALTIMESTAMP FROM deleted
+ON CONFLICT (feed_id, url) DO UPDATE SET pruned_at = EXCLUDED.pruned_at, last_seen_at = EXCLUDED.last_seen_at
+`
+
+type DeleteFeedPostsBeyondLimitParams struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Keep   int32     `json:"keep"`
+}
+
+func (q *Queries) DeleteFeedPostsBeyondLimit(ctx context.Context, arg DeleteFeedPostsBeyondLimitParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedPostsBeyondLimit, arg.FeedID, arg.Keep)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteFeedPostsOlderThan = `-- name: DeleteFeedPostsOlderThan :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.feed_id = $1 AND COALESCE(posts.published_at, posts.created_at) < $2::timestamp
+    AND NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+    RETURNING posts.feed_id, posts.url
+)
+INSERT INTO pruned_posts (feed_id, url, pruned_at, last_seen_at)
+SELECT deleted.feed_id, deleted.url, LOCALTIMESTAMP, LOCALTIMESTAMP FROM deleted
+ON CONFLICT (feed_id, url) DO UPDATE SET pruned_at = EXCLUDED.pruned_at, last_seen_at = EXCLUDED.last_seen_at
+`
+
+type DeleteFeedPostsOlderThanParams struct {
+	FeedID uuid.UUID `json:"feed_id"`
+	Cutoff time.Time `json:"cutoff"`
+}
+
+func (q *Queries) DeleteFeedPostsOlderThan(ctx context.Context, arg DeleteFeedPostsOlderThanParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedPostsOlderThan, arg.FeedID, arg.Cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getPostByID = `-- name: GetPostByID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, canonical_url, fingerprint, cluster_id, search_config, search_vector, author FROM posts
+WHERE id = $1
+`
+
+func (q *Queries) GetPostByID(ctx context.Context, id uuid.UUID) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByID, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.CanonicalUrl,
+		&i.Fingerprint,
+		&i.ClusterID,
+		&i.SearchConfig,
+		&i.SearchVector,
+		&i.Author,
+	)
+	return i, err
+}
+
 const getPostsByUser = `-- name: GetPostsByUser :many
-SELECT id, created_at, updated_at, title, url, description, published_at, feed_id FROM posts
-WHERE feed_id IN (
-    SELECT feed_id FROM feed_follows
-    WHERE user_id = $1
+WITH matching AS NOT MATERIALIZED (
+    SELECT * FROM posts
+    WHERE feed_id IN (
+        SELECT feed_id FROM feed_follows
+        WHERE user_id = $1
+        -- Feeds hidden from the main timeline still show up when browsing a folder, a saved search or the feed itself,
+        -- & with all_follows
+        AND (show_in_timeline OR $2::uuid IS NOT NULL OR $3::uuid IS NOT NULL
+            OR $4::text IS NOT NULL OR $5::boolean)
+    )
+    AND ($3::uuid IS NULL OR posts.feed_id = $3::uuid)
+    AND ($2::uuid IS NULL OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows
+        JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+        WHERE feed_follows.user_id = $1 AND feed_follow_folders.folder_id = $2::uuid
+    ))
+    AND ($6::boolean OR NOT EXISTS (
+        SELECT 1 FROM hidden_posts
+        WHERE hidden_posts.user_id = $1 AND hidden_posts.post_id = posts.id
+    ))
+    AND ($7::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.user_id = $1 AND post_tags.post_id = posts.id AND post_tags.tag = $7::text
+    ))
+    AND ($4::text IS NULL OR (posts.search_vector @@ posts_search_query($4::text)
+        AND posts.search_vector @@ websearch_to_tsquery(posts.search_config, $4::text)))
+    AND ($8::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= $8::timestamp)
+    AND ($9::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < $9::timestamp)
+    AND ($10::boolean IS NULL OR COALESCE(
+        (SELECT is_read FROM user_post_state
+        WHERE user_post_state.user_id = $1 AND user_post_state.post_id = posts.id),
+        (SELECT posts.created_at <= read_before FROM read_watermarks
+        WHERE read_watermarks.user_id = $1 AND read_watermarks.feed_id = posts.feed_id),
+        false
+    ) = $10::boolean)
+    AND ($11::boolean IS NULL OR EXISTS (
+        SELECT 1 FROM starred_posts
+        WHERE starred_posts.user_id = $1 AND starred_posts.post_id = posts.id
+    ) = $11::boolean)
 )
-ORDER BY published_at DESC
-LIMIT $2
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, canonical_url, fingerprint, cluster_id, search_config, search_vector, author FROM matching
+-- A collapsed story is shown as its earliest post that passes the filters
+WHERE (NOT $12::boolean OR NOT EXISTS (
+    SELECT 1 FROM matching other
+    WHERE other.cluster_id = matching.cluster_id
+    AND (other.created_at, other.id) < (matching.created_at, matching.id)
+))
+AND ($13::timestamp IS NULL
+    OR ($14::boolean AND (COALESCE(matching.published_at, matching.created_at), matching.id) > ($13::timestamp, $15::uuid))
+    OR (NOT $14::boolean AND (COALESCE(matching.published_at, matching.created_at), matching.id) < ($13::timestamp, $15::uuid))
+)
+ORDER BY
+    CASE WHEN $14::boolean THEN COALESCE(matching.published_at, matching.created_at) END ASC,
+    CASE WHEN $14::boolean THEN matching.id END ASC,
+    COALESCE(matching.published_at, matching.created_at) DESC,
+    matching.id DESC
+LIMIT $16
 `
 
 type GetPostsByUserParams struct {
-	UserID uuid.UUID `json:"user_id"`
-	Limit  int32     `json:"limit"`
+	UserID        uuid.UUID      `json:"user_id"`
+	FolderID      uuid.NullUUID  `json:"folder_id"`
+	FeedID        uuid.NullUUID  `json:"feed_id"`
+	Query         sql.NullString `json:"query"`
+	AllFollows    bool           `json:"all_follows"`
+	IncludeHidden bool           `json:"include_hidden"`
+	Tag           sql.NullString `json:"tag"`
+	Since         sql.NullTime   `json:"since"`
+	Until         sql.NullTime   `json:"until"`
+	IsRead        sql.NullBool   `json:"is_read"`
+	Starred       sql.NullBool   `json:"starred"`
+	Collapse      bool           `json:"collapse"`
+	CursorTime    sql.NullTime   `json:"cursor_time"`
+	OldestFirst   bool           `json:"oldest_first"`
+	CursorID      uuid.NullUUID  `json:"cursor_id"`
+	LimitCount    int32          `json:"limit_count"`
 }
 
 func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsByUser,
+		arg.UserID,
+		arg.FolderID,
+		arg.FeedID,
+		arg.Query,
+		arg.AllFollows,
+		arg.IncludeHidden,
+		arg.Tag,
+		arg.Since,
+		arg.Until,
+		arg.IsRead,
+		arg.Starred,
+		arg.Collapse,
+		arg.CursorTime,
+		arg.OldestFirst,
+		arg.CursorID,
+		arg.LimitCount,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +249,13 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.CanonicalUrl,
+			&i.Fingerprint,
+			&i.ClusterID,
+			&i.SearchConfig,
+			&i.SearchVector,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -84,3 +269,165 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const getRecentFingerprints = `-- name: GetRecentFingerprints :many
+SELECT cluster_id, fingerprint FROM posts
+WHERE created_at > $1::timestamp AND id <> $2 AND feed_id <> $3
+-- Fingerprints within 3 bits of each other share at least one of their four 16-bit bands, see fingerprintBands()
+AND (
+    (fingerprint >> 48) & 65535 = $4::bigint
+    OR (fingerprint >> 32) & 65535 = $5::bigint
+    OR (fingerprint >> 16) & 65535 = $6::bigint
+    OR fingerprint & 65535 = $7::bigint
+)
+`
+
+type GetRecentFingerprintsParams struct {
+	Since     time.Time `json:"since"`
+	ExcludeID uuid.UUID `json:"exclude_id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	Band0     int64     `json:"band_0"`
+	Band1     int64     `json:"band_1"`
+	Band2     int64     `json:"band_2"`
+	Band3     int64     `json:"band_3"`
+}
+
+type GetRecentFingerprintsRow struct {
+	ClusterID   uuid.UUID     `json:"cluster_id"`
+	Fingerprint sql.NullInt64 `json:"fingerprint"`
+}
+
+func (q *Queries) GetRecentFingerprints(ctx context.Context, arg GetRecentFingerprintsParams) ([]GetRecentFingerprintsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentFingerprints,
+		arg.Since,
+		arg.ExcludeID,
+		arg.FeedID,
+		arg.Band0,
+		arg.Band1,
+		arg.Band2,
+		arg.Band3,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentFingerprintsRow
+	for rows.Next() {
+		var i GetRecentFingerprintsRow
+		if err := rows.Scan(&i.ClusterID, &i.Fingerprint); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStoryClusterByCanonicalURL = `-- name: GetStoryClusterByCanonicalURL :one
+SELECT cluster_id FROM posts
+WHERE canonical_url = $1 AND id <> $2
+ORDER BY created_at
+LIMIT 1
+`
+
+type GetStoryClusterByCanonicalURLParams struct {
+	CanonicalUrl string    `json:"canonical_url"`
+	ID           uuid.UUID `json:"id"`
+}
+
+func (q *Queries) GetStoryClusterByCanonicalURL(ctx context.Context, arg GetStoryClusterByCanonicalURLParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getStoryClusterByCanonicalURL, arg.CanonicalUrl, arg.ID)
+	var clusterID uuid.UUID
+	err := row.Scan(&clusterID)
+	return clusterID, err
+}
+
+const getStoryClusterMembers = `-- name: GetStoryClusterMembers :many
+SELECT posts.id, posts.cluster_id, posts.feed_id, posts.title, posts.url, COALESCE(feed_follows.title, feeds.name)::text AS feed_name
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+WHERE posts.cluster_id = ANY($2::uuid[])
+ORDER BY posts.created_at
+`
+
+type GetStoryClusterMembersParams struct {
+	UserID     uuid.UUID   `json:"user_id"`
+	ClusterIds []uuid.UUID `json:"cluster_ids"`
+}
+
+type GetStoryClusterMembersRow struct {
+	ID        uuid.UUID `json:"id"`
+	ClusterID uuid.UUID `json:"cluster_id"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	Title     string    `json:"title"`
+	Url       string    `json:"url"`
+	FeedName  string    `json:"feed_name"`
+}
+
+func (q *Queries) GetStoryClusterMembers(ctx context.Context, arg GetStoryClusterMembersParams) ([]GetStoryClusterMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, getStoryClusterMembers, arg.UserID, pq.Array(arg.ClusterIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetStoryClusterMembersRow
+	for rows.Next() {
+		var i GetStoryClusterMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClusterID,
+			&i.FeedID,
+			&i.Title,
+			&i.Url,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostCluster = `-- name: SetPostCluster :exec
+UPDATE posts
+SET cluster_id = $2
+WHERE id = $1
+`
+
+type SetPostClusterParams struct {
+	ID        uuid.UUID `json:"id"`
+	ClusterID uuid.UUID `json:"cluster_id"`
+}
+
+func (q *Queries) SetPostCluster(ctx context.Context, arg SetPostClusterParams) error {
+	_, err := q.db.ExecContext(ctx, setPostCluster, arg.ID, arg.ClusterID)
+	return err
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET updated_at = LOCALTIMESTAMP, content = $2
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID      uuid.UUID      `json:"id"`
+	Content sql.NullString `json:"content"`
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content)
+	return err
+}
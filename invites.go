@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Who can create an account with POST /v1/users
+const (
+	registrationOpen   = "open"
+	registrationInvite = "invite"
+	registrationClosed = "closed"
+)
+
+// Length of generated invite codes, in hex characters
+const inviteCodeLength = 16
+
+// Used in databaseInviteCodeToInviteCode()
+type InviteCode struct {
+	ID        uuid.UUID  `json:"id"`
+	Code      string     `json:"code"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	MaxUses   int32      `json:"max_uses"`
+	Uses      int32      `json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Generates an invite code, usable max_uses times (1 by default) until it expires
+func (cfg *apiConfig) handlerInviteCodesPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		MaxUses   *int32     `json:"max_uses"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	maxUses := int32(1)
+	if params.MaxUses != nil {
+		if *params.MaxUses < 1 {
+			respondWithError(w, 400, "max_uses must be at least 1")
+			return
+		}
+		maxUses = *params.MaxUses
+	}
+	var expiresAt sql.NullTime
+	if params.ExpiresAt != nil {
+		if params.ExpiresAt.Before(time.Now()) {
+			respondWithError(w, 400, "Expiry must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: *params.ExpiresAt, Valid: true}
+	}
+
+	code, err := generateToken()
+	if err != nil {
+		log.Printf("Error generating invite code: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	invite, err := cfg.DB.CreateInviteCode(r.Context(), database.CreateInviteCodeParams{
+		ID:        uuid.New(),
+		Code:      code[:inviteCodeLength],
+		CreatedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+		MaxUses:   maxUses,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error creating invite code: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 201, databaseInviteCodeToInviteCode(invite))
+}
+
+// Lists every invite code, newest first
+func (cfg *apiConfig) handlerInviteCodesGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	invites, err := cfg.DB.GetInviteCodes(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	payload := []InviteCode{}
+	for _, invite := range invites {
+		payload = append(payload, databaseInviteCodeToInviteCode(invite))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Revokes an invite code. Accounts already created with it are unaffected
+func (cfg *apiConfig) handlerInviteCodesDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	inviteID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid InviteCodeID")
+		return
+	}
+	deleted, err := cfg.DB.DeleteInviteCode(r.Context(), inviteID)
+	if err != nil {
+		log.Printf("Error deleting invite code: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Invite code not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Helper func that converts database.InviteCode to InviteCode
+func databaseInviteCodeToInviteCode(dbInvite database.InviteCode) InviteCode {
+	invite := InviteCode{
+		ID:        dbInvite.ID,
+		Code:      dbInvite.Code,
+		MaxUses:   dbInvite.MaxUses,
+		Uses:      dbInvite.Uses,
+		CreatedAt: dbInvite.CreatedAt,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbInvite.CreatedBy.Valid {
+		invite.CreatedBy = &dbInvite.CreatedBy.UUID
+	}
+	if dbInvite.ExpiresAt.Valid {
+		invite.ExpiresAt = &dbInvite.ExpiresAt.Time
+	}
+	return invite
+}
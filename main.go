@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -28,7 +30,15 @@ type authedHandler func(http.ResponseWriter, *http.Request, database.User)
 
 // For accessing the DB server, used in main()
 type apiConfig struct {
-	DB *database.Queries
+	DB                 *database.Queries
+	DBConn             *sql.DB
+	PublicURL          string
+	ImageProxyKey      []byte
+	ImageCache         *imageCache
+	Retention          retentionPolicy
+	DormantAfterDays   int
+	RateLimiter        *rateLimiter
+	RegistrationPolicy string
 }
 
 // dateLayouts is a slice of potential date layouts RSS feeds might use
@@ -39,27 +49,70 @@ var dateLayouts = []string{
 	// Add more layouts as needed
 }
 
+// Used in databaseUserToUser(). APIKey is only set when the user is first created
+type User struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Name        string          `json:"name"`
+	Email       *string         `json:"email"`
+	IsAdmin     bool            `json:"is_admin"`
+	Preferences json.RawMessage `json:"preferences"`
+	DisabledAt  *time.Time      `json:"disabled_at"`
+	APIKey      string          `json:"api_key,omitempty"`
+}
+
 // Used in databaseFeedToFeed()
 type Feed struct {
-	ID            uuid.UUID  `json:"id"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	Name          string     `json:"name"`
-	Url           string     `json:"url"`
-	UserID        uuid.UUID  `json:"user_id"`
-	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	ID                  uuid.UUID  `json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	Name                string     `json:"name"`
+	Url                 string     `json:"url"`
+	UserID              *uuid.UUID `json:"user_id"`
+	LastFetchedAt       *time.Time `json:"last_fetched_at"`
+	FetchFullText       bool       `json:"fetch_full_text"`
+	RetentionKeep       *int32     `json:"retention_keep"`
+	RetentionMaxAgeDays *int32     `json:"retention_max_age_days"`
+	LastPostAt          *time.Time `json:"last_post_at"`
+	Dormant             bool       `json:"dormant"`
+}
+
+// Used in databaseFeedFollowToFeedFollow(). FeedTitle is the custom title, or the feed's name if none is set
+type FeedFollow struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	UserID         uuid.UUID `json:"user_id"`
+	FeedID         uuid.UUID `json:"feed_id"`
+	FeedTitle      string    `json:"feed_title,omitempty"`
+	Title          *string   `json:"title"`
+	Notes          string    `json:"notes"`
+	Priority       int32     `json:"priority"`
+	ShowInTimeline bool      `json:"show_in_timeline"`
+	// Set on saved searches listed with the follows, see savedSearchToFeedFollow()
+	Virtual       bool       `json:"virtual"`
+	SavedSearchID *uuid.UUID `json:"saved_search_id,omitempty"`
 }
 
 // Used in databasePostToPost()
 type Post struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Title       string    `json:"title"`
-	Url         string    `json:"url"`
-	Description string    `json:"description"`
-	PublishedAt time.Time `json:"published_at"`
-	FeedID      uuid.UUID `json:"feed_id"`
+	ID             uuid.UUID            `json:"id"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+	Title          string               `json:"title"`
+	Url            string               `json:"url"`
+	Description    string               `json:"description"`
+	Author         string               `json:"author"`
+	Content        string               `json:"content"`
+	PublishedAt    time.Time            `json:"published_at"`
+	FeedID         uuid.UUID            `json:"feed_id"`
+	FeedTitle      string               `json:"feed_title"`
+	ClusterID      uuid.UUID            `json:"cluster_id"`
+	Read           bool                 `json:"read"`
+	Starred        bool                 `json:"starred"`
+	Tags           []string             `json:"tags"`
+	AlsoReportedBy []StoryClusterMember `json:"also_reported_by,omitempty"`
 }
 
 // Structs for RSS Feed data
@@ -71,6 +124,7 @@ type Channel struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
+	Language    string `xml:"language"`
 	Items       []Item `xml:"item"`
 }
 
@@ -79,6 +133,8 @@ type Item struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
 }
 
 func main() {
@@ -95,26 +151,147 @@ func main() {
 	dbQueries := database.New(db)
 	apiCfg := apiConfig{}
 	apiCfg.DB = dbQueries
+	apiCfg.DBConn = db
+
+	// Users promoted to admin on startup, as a comma-separated list of user IDs. Used to bootstrap the first admin
+	adminIDs := []uuid.UUID{}
+	for _, idStr := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		if idStr = strings.TrimSpace(idStr); idStr == "" {
+			continue
+		}
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			log.Fatalf("Invalid ID in ADMIN_USER_IDS: %v", idStr)
+		}
+		adminIDs = append(adminIDs, id)
+	}
+	if len(adminIDs) > 0 {
+		promoted, err := dbQueries.PromoteAdmins(context.Background(), adminIDs)
+		if err != nil {
+			log.Fatalf("Could not promote admins: %v", err)
+		}
+		if promoted > 0 {
+			log.Printf("Promoted %v users to admin", promoted)
+		}
+	}
+
+	// Key for signing image proxy URLs. Without one, a random key is used & proxied URLs stop working on restart
+	apiCfg.PublicURL = strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	apiCfg.ImageProxyKey = []byte(os.Getenv("IMAGE_PROXY_KEY"))
+	if len(apiCfg.ImageProxyKey) == 0 {
+		log.Println("IMAGE_PROXY_KEY is not set, using a random key")
+		apiCfg.ImageProxyKey = make([]byte, 32)
+		if _, err := rand.Read(apiCfg.ImageProxyKey); err != nil {
+			log.Fatalf("Could not generate image proxy key: %v", err)
+		}
+	}
+	apiCfg.ImageCache = newImageCache(imageCacheSize)
+
+	// Default retention policy for feeds without their own, unset means posts are kept forever
+	apiCfg.Retention.Keep = envInt("RETENTION_KEEP", 0)
+	apiCfg.Retention.MaxAgeDays = envInt("RETENTION_MAX_AGE_DAYS", 0)
+
+	// Feeds without a new post in this many days are flagged as dormant & fetched less often
+	apiCfg.DormantAfterDays = envInt("DORMANT_AFTER_DAYS", 90)
+
+	// Requests per minute allowed for each API key or session, for each IP overall, & for each IP on unauthenticated routes
+	apiCfg.RateLimiter = newRateLimiter(dbQueries)
+
+	// Who can sign up: anyone ("open"), only with an invite code from an admin ("invite"), or nobody ("closed")
+	apiCfg.RegistrationPolicy = os.Getenv("REGISTRATION_POLICY")
+	if apiCfg.RegistrationPolicy == "" {
+		apiCfg.RegistrationPolicy = registrationOpen
+	}
+	if apiCfg.RegistrationPolicy != registrationOpen && apiCfg.RegistrationPolicy != registrationInvite && apiCfg.RegistrationPolicy != registrationClosed {
+		log.Fatalf("Invalid REGISTRATION_POLICY: %v", apiCfg.RegistrationPolicy)
+	}
 
 	// Routers & endpoints
 	v1Router := chi.NewRouter()
-	v1Router.Post("/users", apiCfg.handlerUsersPost)
+	v1Router.Post("/users", apiCfg.middlewareRateLimitIP(apiCfg.handlerUsersPost))
 	v1Router.Get("/users", apiCfg.middlewareAuth(apiCfg.handlerUsersGet))
-	v1Router.Post("/feeds", apiCfg.middlewareAuth(apiCfg.handlerFeedsPost))
-	v1Router.Get("/feeds", apiCfg.handlerFeedsGet)
-	v1Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsPost))
-	v1Router.Delete("/feed_follows/{id}", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsDelete))
-	v1Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsGet))
-	v1Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.handlerPostsGet))
+	v1Router.Patch("/users", apiCfg.middlewareAuth(apiCfg.handlerUsersPatch, scopeAccount))
+	v1Router.Delete("/users", apiCfg.middlewareAuth(apiCfg.handlerUsersDelete, scopeAccount))
+	v1Router.Put("/users/password", apiCfg.middlewareAuth(apiCfg.handlerUsersPasswordPut, scopeAll))
+	v1Router.Get("/users/export", apiCfg.middlewareAuth(apiCfg.handlerUsersExportGet, scopeAccount))
+	v1Router.Get("/users/export/{id}", apiCfg.middlewareAuth(apiCfg.handlerUsersExportJobGet, scopeAccount))
+	v1Router.Get("/users/export/{id}/download", apiCfg.middlewareAuth(apiCfg.handlerUsersExportJobDownloadGet, scopeAccount))
+	v1Router.Post("/login", apiCfg.middlewareRateLimitIP(apiCfg.handlerLoginPost))
+	v1Router.Post("/refresh", apiCfg.middlewareRateLimitIP(apiCfg.handlerRefreshPost))
+	v1Router.Post("/logout", apiCfg.middlewareAuth(apiCfg.handlerLogoutPost))
+	v1Router.Post("/api_keys", apiCfg.middlewareAuth(apiCfg.handlerAPIKeysPost, scopeKeysWrite))
+	v1Router.Get("/api_keys", apiCfg.middlewareAuth(apiCfg.handlerAPIKeysGet, scopeKeysWrite))
+	v1Router.Delete("/api_keys/{id}", apiCfg.middlewareAuth(apiCfg.handlerAPIKeysDelete, scopeKeysWrite))
+	v1Router.Post("/feeds", apiCfg.middlewareAuth(apiCfg.handlerFeedsPost, scopeFeedsWrite, scopeFollowsWrite))
+	v1Router.Get("/feeds", apiCfg.middlewareRateLimitPublic(apiCfg.handlerFeedsGet))
+	v1Router.Patch("/feeds/{id}", apiCfg.middlewareAuth(apiCfg.handlerFeedsPatch, scopeFeedsWrite))
+	v1Router.Post("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsPost, scopeFollowsWrite))
+	v1Router.Delete("/feed_follows/{id}", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsDelete, scopeFollowsWrite))
+	v1Router.Patch("/feed_follows/{id}", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsPatch, scopeFollowsWrite))
+	v1Router.Get("/feed_follows", apiCfg.middlewareAuth(apiCfg.handlerFeedFollowsGet, scopeFollowsRead))
+	v1Router.Post("/filter_rules", apiCfg.middlewareAuth(apiCfg.handlerFilterRulesPost, scopeFollowsWrite))
+	v1Router.Get("/filter_rules", apiCfg.middlewareAuth(apiCfg.handlerFilterRulesGet, scopeFollowsRead))
+	v1Router.Delete("/filter_rules/{id}", apiCfg.middlewareAuth(apiCfg.handlerFilterRulesDelete, scopeFollowsWrite))
+	v1Router.Post("/filter_rules/test", apiCfg.middlewareAuth(apiCfg.handlerFilterRulesTestPost, scopePostsRead))
+	v1Router.Post("/saved_searches", apiCfg.middlewareAuth(apiCfg.handlerSavedSearchesPost, scopeFollowsWrite))
+	v1Router.Get("/saved_searches", apiCfg.middlewareAuth(apiCfg.handlerSavedSearchesGet, scopeFollowsRead))
+	v1Router.Patch("/saved_searches/{id}", apiCfg.middlewareAuth(apiCfg.handlerSavedSearchesPatch, scopeFollowsWrite))
+	v1Router.Delete("/saved_searches/{id}", apiCfg.middlewareAuth(apiCfg.handlerSavedSearchesDelete, scopeFollowsWrite))
+	v1Router.Post("/folders", apiCfg.middlewareAuth(apiCfg.handlerFoldersPost, scopeFollowsWrite))
+	v1Router.Get("/folders", apiCfg.middlewareAuth(apiCfg.handlerFoldersGet, scopeFollowsRead))
+	v1Router.Patch("/folders/{id}", apiCfg.middlewareAuth(apiCfg.handlerFoldersPatch, scopeFollowsWrite))
+	v1Router.Delete("/folders/{id}", apiCfg.middlewareAuth(apiCfg.handlerFoldersDelete, scopeFollowsWrite))
+	v1Router.Put("/folders/{id}/feed_follows/{feed_follow_id}", apiCfg.middlewareAuth(apiCfg.handlerFolderFeedFollowPut, scopeFollowsWrite))
+	v1Router.Delete("/folders/{id}/feed_follows/{feed_follow_id}", apiCfg.middlewareAuth(apiCfg.handlerFolderFeedFollowDelete, scopeFollowsWrite))
+	v1Router.Get("/posts", apiCfg.middlewareAuth(apiCfg.handlerPostsGet, scopePostsRead))
+	v1Router.Get("/search", apiCfg.middlewareAuth(apiCfg.handlerSearchGet, scopePostsRead))
+	v1Router.Get("/posts/unread_counts", apiCfg.middlewareAuth(apiCfg.handlerUnreadCountsGet, scopePostsRead))
+	v1Router.Post("/posts/read", apiCfg.middlewareAuth(apiCfg.handlerPostsReadPost, scopePostsWrite))
+	v1Router.Post("/posts/unread", apiCfg.middlewareAuth(apiCfg.handlerPostsUnreadPost, scopePostsWrite))
+	v1Router.Post("/posts/mark_all_read", apiCfg.middlewareAuth(apiCfg.handlerPostsMarkAllReadPost, scopePostsWrite))
+	v1Router.Post("/posts/{id}/read", apiCfg.middlewareAuth(apiCfg.handlerPostReadPost, scopePostsWrite))
+	v1Router.Post("/posts/{id}/unread", apiCfg.middlewareAuth(apiCfg.handlerPostUnreadPost, scopePostsWrite))
+	v1Router.Post("/posts/{id}/tags", apiCfg.middlewareAuth(apiCfg.handlerPostTagsPost, scopePostsWrite))
+	v1Router.Delete("/posts/{id}/tags/{tag}", apiCfg.middlewareAuth(apiCfg.handlerPostTagsDelete, scopePostsWrite))
+	v1Router.Get("/tags", apiCfg.middlewareAuth(apiCfg.handlerTagsGet, scopePostsRead))
+	v1Router.Delete("/tags/{tag}", apiCfg.middlewareAuth(apiCfg.handlerTagsDelete, scopePostsWrite))
+	v1Router.Get("/posts/starred", apiCfg.middlewareAuth(apiCfg.handlerStarredPostsGet, scopePostsRead))
+	v1Router.Post("/posts/{id}/star", apiCfg.middlewareAuth(apiCfg.handlerPostStarPost, scopePostsWrite))
+	v1Router.Delete("/posts/{id}/star", apiCfg.middlewareAuth(apiCfg.handlerPostStarDelete, scopePostsWrite))
 	v1Router.Get("/readiness", handlerReadinessGet)
 	v1Router.Get("/err", errTest)
 
+	adminRouter := chi.NewRouter()
+	adminRouter.Get("/extraction_rules", apiCfg.middlewareAdmin(apiCfg.handlerExtractionRulesGet))
+	adminRouter.Put("/extraction_rules", apiCfg.middlewareAdmin(apiCfg.handlerExtractionRulesPut))
+	adminRouter.Delete("/extraction_rules/{id}", apiCfg.middlewareAdmin(apiCfg.handlerExtractionRulesDelete))
+	adminRouter.Put("/feeds/{id}/retention", apiCfg.middlewareAdmin(apiCfg.handlerFeedRetentionPut))
+	adminRouter.Post("/retention/run", apiCfg.middlewareAdmin(apiCfg.handlerRetentionRunPost))
+	adminRouter.Get("/feeds/orphaned", apiCfg.middlewareAdmin(apiCfg.handlerOrphanedFeedsGet))
+	adminRouter.Delete("/feeds/orphaned", apiCfg.middlewareAdmin(apiCfg.handlerOrphanedFeedsDelete))
+	adminRouter.Delete("/feeds/{id}", apiCfg.middlewareAdmin(apiCfg.handlerAdminFeedsDelete))
+	adminRouter.Post("/invite_codes", apiCfg.middlewareAdmin(apiCfg.handlerInviteCodesPost))
+	adminRouter.Get("/invite_codes", apiCfg.middlewareAdmin(apiCfg.handlerInviteCodesGet))
+	adminRouter.Delete("/invite_codes/{id}", apiCfg.middlewareAdmin(apiCfg.handlerInviteCodesDelete))
+	adminRouter.Get("/users", apiCfg.middlewareAdmin(apiCfg.handlerAdminUsersGet))
+	adminRouter.Post("/users/{id}/disable", apiCfg.middlewareAdmin(apiCfg.handlerAdminUsersDisablePost))
+	adminRouter.Post("/users/{id}/enable", apiCfg.middlewareAdmin(apiCfg.handlerAdminUsersEnablePost))
+	adminRouter.Post("/users/{id}/impersonate", apiCfg.middlewareAdmin(apiCfg.handlerAdminUsersImpersonatePost))
+	adminRouter.Delete("/users/{id}", apiCfg.middlewareAdmin(apiCfg.handlerAdminUsersDelete))
+	v1Router.Mount("/admin", adminRouter)
+
 	mainRouter := chi.NewRouter()
 	mainRouter.Use(cors.Handler(cors.Options{}))
 	mainRouter.Mount("/v1", v1Router)
+	mainRouter.Get("/imageproxy/{signature}/{url}", apiCfg.middlewareRateLimitPublic(apiCfg.handlerImageProxy))
 
 	// Start the worker for fetching feeds
 	go apiCfg.fetchFeedsWorker()
+	go apiCfg.retentionWorker()
+	go apiCfg.dormantFeedsWorker()
+	if _, ok := apiCfg.RateLimiter.store.(*postgresRateLimitStore); ok {
+		go apiCfg.rateLimitCleanupWorker()
+	}
 
 	// Initialize server & starts listening for connections
 	srv := &http.Server{
@@ -128,7 +305,10 @@ func main() {
 // Creates a user in the DB
 func (cfg *apiConfig) handlerUsersPost(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Name string `json:"name"`
+		Name       string `json:"name"`
+		Email      string `json:"email"`
+		Password   string `json:"password"`
+		InviteCode string `json:"invite_code"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -144,32 +324,198 @@ func (cfg *apiConfig) handlerUsersPost(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 400, "Name cannot be empty")
 		return
 	}
+	if cfg.RegistrationPolicy == registrationClosed {
+		respondWithErrorCode(w, 403, "registration_closed", "Registration is closed")
+		return
+	}
+	if cfg.RegistrationPolicy == registrationInvite && params.InviteCode == "" {
+		respondWithErrorCode(w, 403, "invite_required", "An invite code is required to register")
+		return
+	}
+
+	// Email & password are optional, users without them can only use API keys
+	var email, passwordHash sql.NullString
+	if params.Email != "" || params.Password != "" {
+		email.String, err = normalizeEmail(params.Email)
+		if err != nil {
+			respondWithError(w, 400, "Invalid email")
+			return
+		}
+		email.Valid = true
+		passwordHash.String, err = hashPassword(params.Password)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+		passwordHash.Valid = true
+		if _, err := cfg.DB.GetUserByEmail(r.Context(), email); err == nil {
+			respondWithError(w, 409, "Email is already registered")
+			return
+		}
+	}
+
+	// The user & their first API key are created together, so a user never ends up without a key
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	// The invite is only used up if the user is actually created
+	if cfg.RegistrationPolicy == registrationInvite {
+		_, err = qtx.UseInviteCode(r.Context(), params.InviteCode)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithErrorCode(w, 403, "invalid_invite", "Invite code is invalid, expired or used up")
+			return
+		}
+		if err != nil {
+			log.Printf("Error using invite code: %s", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+	}
 
 	userParams := database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      params.Name,
+		ID:           uuid.New(),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		Name:         params.Name,
+		Email:        email,
+		PasswordHash: passwordHash,
 	}
-	user, err := cfg.DB.CreateUser(r.Context(), userParams)
+	dbUser, err := qtx.CreateUser(r.Context(), userParams)
 	if err != nil {
 		log.Printf("Error creating user: %s", err)
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
+	key, err := createAPIKey(r.Context(), qtx, dbUser.ID, "default", sql.NullTime{}, []string{scopeAll})
+	if err != nil {
+		log.Printf("Error creating API key: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	user := databaseUserToUser(dbUser)
+	user.APIKey = key.Key
 	respondWithJSON(w, 201, user)
 }
 
 // Retrieves authenticated user
 func (cfg *apiConfig) handlerUsersGet(w http.ResponseWriter, r *http.Request, user database.User) {
-	respondWithJSON(w, 200, user)
+	respondWithJSON(w, 200, databaseUserToUser(user))
+}
+
+// Updates the authenticated user's profile. Omitted fields are left unchanged, preferences are replaced as a whole
+func (cfg *apiConfig) handlerUsersPatch(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name        *string         `json:"name"`
+		Email       *string         `json:"email"`
+		Preferences json.RawMessage `json:"preferences"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	updateParams := database.UpdateUserParams{
+		ID:          user.ID,
+		Name:        user.Name,
+		Email:       user.Email,
+		Preferences: user.Preferences,
+	}
+	if params.Name != nil {
+		if *params.Name == "" {
+			respondWithError(w, 400, "Name cannot be empty")
+			return
+		}
+		updateParams.Name = *params.Name
+	}
+	if params.Email != nil {
+		// An email & password can be used to log in with full access, so setting one takes a full access key
+		if scope := missingScope(grantedScopes(r), []string{scopeAll}); scope != "" {
+			respondWithMissingScope(w, scope)
+			return
+		}
+		email, err := normalizeEmail(*params.Email)
+		if err != nil {
+			respondWithError(w, 400, "Invalid email")
+			return
+		}
+		updateParams.Email = sql.NullString{String: email, Valid: true}
+		if existing, err := cfg.DB.GetUserByEmail(r.Context(), updateParams.Email); err == nil && existing.ID != user.ID {
+			respondWithError(w, 409, "Email is already registered")
+			return
+		}
+	}
+	if params.Preferences != nil {
+		var preferences map[string]any
+		if err := json.Unmarshal(params.Preferences, &preferences); err != nil || preferences == nil {
+			respondWithError(w, 400, "Preferences must be a JSON object")
+			return
+		}
+		updateParams.Preferences = params.Preferences
+	}
+
+	dbUser, err := cfg.DB.UpdateUser(r.Context(), updateParams)
+	if err != nil {
+		log.Printf("Error updating user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, databaseUserToUser(dbUser))
+}
+
+// Deletes the authenticated user's account
+func (cfg *apiConfig) handlerUsersDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	_, err := cfg.deleteUser(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error deleting user: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Deletes a user, their follows, keys & sessions go with them. Feeds they created are handed to their oldest
+// other follower, or left without an owner if nobody else follows them. Returns the number of users deleted
+func (cfg *apiConfig) deleteUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	tx, err := cfg.DBConn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	err = qtx.ReassignUserFeeds(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("reassign feeds: %v", err)
+	}
+	deleted, err := qtx.DeleteUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
 }
 
 // Create a feed in the DB
 func (cfg *apiConfig) handlerFeedsPost(w http.ResponseWriter, r *http.Request, user database.User) {
 	type parameters struct {
-		Name string `json:"name"`
-		URL  string `json:"url"`
+		Name          string `json:"name"`
+		URL           string `json:"url"`
+		FetchFullText bool   `json:"fetch_full_text"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -187,12 +533,13 @@ func (cfg *apiConfig) handlerFeedsPost(w http.ResponseWriter, r *http.Request, u
 	}
 
 	feedParams := database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      params.Name,
-		Url:       params.URL,
-		UserID:    user.ID,
+		ID:            uuid.New(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		Name:          params.Name,
+		Url:           params.URL,
+		UserID:        uuid.NullUUID{UUID: user.ID, Valid: true},
+		FetchFullText: params.FetchFullText,
 	}
 	dbFeed, err := cfg.DB.CreateFeed(r.Context(), feedParams)
 	if err != nil {
@@ -217,11 +564,11 @@ func (cfg *apiConfig) handlerFeedsPost(w http.ResponseWriter, r *http.Request, u
 	}
 
 	payload := struct {
-		Feed       Feed                `json:"feed"`
-		FeedFollow database.FeedFollow `json:"feed_follow"`
+		Feed       Feed       `json:"feed"`
+		FeedFollow FeedFollow `json:"feed_follow"`
 	}{
 		Feed:       feed,
-		FeedFollow: feedFollow,
+		FeedFollow: databaseFeedFollowToFeedFollow(feedFollow),
 	}
 	respondWithJSON(w, 201, payload)
 }
@@ -233,7 +580,59 @@ func (cfg *apiConfig) handlerFeedsGet(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, 500, "Internal Server Error")
 		return
 	}
-	respondWithJSON(w, 200, feeds)
+
+	payload := []Feed{}
+	for _, dbFeed := range feeds {
+		payload = append(payload, databaseFeedToFeed(dbFeed))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Updates a feed's settings, only allowed for the user who created it
+func (cfg *apiConfig) handlerFeedsPatch(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FeedID")
+		return
+	}
+	type parameters struct {
+		FetchFullText *bool `json:"fetch_full_text"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	dbFeed, err := cfg.DB.GetFeedByID(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Feed not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if !dbFeed.UserID.Valid || dbFeed.UserID.UUID != user.ID {
+		respondWithError(w, 403, "Only the feed's creator can change it")
+		return
+	}
+
+	if params.FetchFullText != nil {
+		dbFeed, err = cfg.DB.SetFeedFetchFullText(r.Context(), database.SetFeedFetchFullTextParams{
+			ID:            feedID,
+			FetchFullText: *params.FetchFullText,
+		})
+		if err != nil {
+			log.Printf("Error updating feed: %s", err)
+			respondWithError(w, 500, "Something went wrong")
+			return
+		}
+	}
+	respondWithJSON(w, 200, databaseFeedToFeed(dbFeed))
 }
 
 // Follows a feed
@@ -269,7 +668,72 @@ func (cfg *apiConfig) handlerFeedFollowsPost(w http.ResponseWriter, r *http.Requ
 		respondWithError(w, 500, "Something went wrong")
 		return
 	}
-	respondWithJSON(w, 201, follow)
+	respondWithJSON(w, 201, databaseFeedFollowToFeedFollow(follow))
+}
+
+// Updates the authenticated user's settings for a followed feed. An empty title goes back to the feed's name
+func (cfg *apiConfig) handlerFeedFollowsPatch(w http.ResponseWriter, r *http.Request, user database.User) {
+	followID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FollowFeedID")
+		return
+	}
+	type parameters struct {
+		Title          *string `json:"title"`
+		Notes          *string `json:"notes"`
+		Priority       *int32  `json:"priority"`
+		ShowInTimeline *bool   `json:"show_in_timeline"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	follow, err := cfg.DB.GetFeedFollowByID(r.Context(), database.GetFeedFollowByIDParams{
+		ID:     followID,
+		UserID: user.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Feed follow not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	// Fields that aren't sent keep their current value
+	updateParams := database.UpdateFeedFollowParams{
+		ID:             follow.ID,
+		UserID:         user.ID,
+		Title:          follow.Title,
+		Notes:          follow.Notes,
+		Priority:       follow.Priority,
+		ShowInTimeline: follow.ShowInTimeline,
+	}
+	if params.Title != nil {
+		updateParams.Title = sql.NullString{String: *params.Title, Valid: *params.Title != ""}
+	}
+	if params.Notes != nil {
+		updateParams.Notes = *params.Notes
+	}
+	if params.Priority != nil {
+		updateParams.Priority = *params.Priority
+	}
+	if params.ShowInTimeline != nil {
+		updateParams.ShowInTimeline = *params.ShowInTimeline
+	}
+	follow, err = cfg.DB.UpdateFeedFollow(r.Context(), updateParams)
+	if err != nil {
+		log.Printf("Error updating feed follow: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, databaseFeedFollowToFeedFollow(follow))
 }
 
 // Unfollows a feed
@@ -295,42 +759,185 @@ func (cfg *apiConfig) handlerFeedFollowsDelete(w http.ResponseWriter, r *http.Re
 	respondWithJSON(w, 200, "OK")
 }
 
-// Gets all followed feeds
+// Gets all followed feeds, or only the ones in ?folder_id=, highest priority first
 func (cfg *apiConfig) handlerFeedFollowsGet(w http.ResponseWriter, r *http.Request, user database.User) {
-	feedFollows, err := cfg.DB.GetFollowedFeeds(r.Context(), user.ID)
+	folderID, err := parseFolderFilter(r)
+	if err != nil {
+		respondWithError(w, 400, "Invalid FolderID")
+		return
+	}
+	var feedFollows []database.FeedFollow
+	if folderID.Valid {
+		feedFollows, err = cfg.DB.GetFollowedFeedsInFolder(r.Context(), database.GetFollowedFeedsInFolderParams{
+			UserID:   user.ID,
+			FolderID: folderID.UUID,
+		})
+	} else {
+		feedFollows, err = cfg.DB.GetFollowedFeeds(r.Context(), user.ID)
+	}
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	titles, err := cfg.feedTitles(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	payload := []FeedFollow{}
+	for _, dbFollow := range feedFollows {
+		follow := databaseFeedFollowToFeedFollow(dbFollow)
+		follow.FeedTitle = titles[follow.FeedID]
+		payload = append(payload, follow)
+	}
+	// Saved searches come after the real follows, in a folder only the ones scoped to it
+	savedSearches, err := cfg.DB.GetSavedSearchesByUser(r.Context(), user.ID)
 	if err != nil {
 		respondWithError(w, 500, "Internal server error")
 		return
 	}
-	respondWithJSON(w, 200, feedFollows)
+	for _, savedSearch := range savedSearches {
+		if folderID.Valid && savedSearch.FolderID != folderID {
+			continue
+		}
+		payload = append(payload, savedSearchToFeedFollow(savedSearch))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// The name the user sees for each followed feed, by feed ID
+func (cfg *apiConfig) feedTitles(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]string, error) {
+	rows, err := cfg.DB.GetFeedTitles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	titles := map[uuid.UUID]string{}
+	for _, row := range rows {
+		titles[row.FeedID] = row.FeedTitle
+	}
+	return titles, nil
+}
+
+// Fills in FeedTitle for each post
+func (cfg *apiConfig) addFeedTitles(ctx context.Context, userID uuid.UUID, posts []Post) error {
+	titles, err := cfg.feedTitles(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].FeedTitle = titles[posts[i].FeedID]
+	}
+	return nil
 }
 
-// Gets all posts for user's followed feeds
+// Gets a page of posts from the user's followed feeds, see parsePostsQuery() for the filters.
+// Pages are keyset on (published_at, id), so new posts arriving between requests don't shift them.
+// ?collapse=true collapses posts about the same story into one entry
 func (cfg *apiConfig) handlerPostsGet(w http.ResponseWriter, r *http.Request, user database.User) {
-	limitStr := r.URL.Query().Get("limit")
-	limitInt := 20
-	if limitStr != "" {
-		if i, err := strconv.Atoi(limitStr); err == nil {
-			limitInt = i
-		}
+	getPostsParams, ok := parsePostsQuery(w, r, user.ID)
+	if !ok {
+		return
 	}
-	getPostsParams := database.GetPostsByUserParams{
-		UserID: user.ID,
-		Limit:  int32(limitInt),
+	// A saved search is read like a feed, its query, scope & any filters it sets replace the request's.
+	// Its sort applies unless ?sort is sent
+	if savedSearchStr := r.URL.Query().Get("saved_search_id"); savedSearchStr != "" {
+		savedSearchID, err := uuid.Parse(savedSearchStr)
+		if err != nil {
+			respondWithError(w, 400, "Invalid SavedSearchID")
+			return
+		}
+		savedSearch, ok := cfg.getSavedSearch(w, r, user.ID, savedSearchID)
+		if !ok {
+			return
+		}
+		getPostsParams.Query = sql.NullString{String: savedSearch.Query, Valid: true}
+		getPostsParams.FeedID = savedSearch.FeedID
+		getPostsParams.FolderID = savedSearch.FolderID
+		if savedSearch.Since.Valid {
+			getPostsParams.Since = savedSearch.Since
+		}
+		if savedSearch.Until.Valid {
+			getPostsParams.Until = savedSearch.Until
+		}
+		if savedSearch.Starred.Valid {
+			getPostsParams.Starred = savedSearch.Starred
+		}
+		if savedSearch.Tag.Valid {
+			getPostsParams.Tag = savedSearch.Tag
+		}
+		if r.URL.Query().Get("sort") == "" {
+			getPostsParams.OldestFirst = savedSearch.Sort == "oldest"
+		}
 	}
+	collapse := getPostsParams.Collapse
+	limit := getPostsParams.LimitCount
+	// One extra post tells whether there's another page
+	getPostsParams.LimitCount++
 
 	posts, err := cfg.DB.GetPostsByUser(r.Context(), getPostsParams)
 	if err != nil {
 		respondWithError(w, 500, "Internal server error")
 		return
 	}
+	var nextCursor *string
+	if len(posts) > int(limit) {
+		posts = posts[:limit]
+		cursor := encodePostCursor(posts[len(posts)-1])
+		nextCursor = &cursor
+	}
 
-	var payload []Post
+	payload := []Post{}
 
-	for _, post := range posts {
-		payload = append(payload, databasePostToPost(post))
+	for _, dbPost := range posts {
+		post := databasePostToPost(dbPost)
+		post.Description = cfg.proxyImages(post.Description, post.Url)
+		post.Content = cfg.proxyImages(post.Content, post.Url)
+		payload = append(payload, post)
 	}
-	respondWithJSON(w, 200, payload)
+	if len(payload) > 0 {
+		err = cfg.addReadState(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting read state: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addStarredState(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting starred state: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addFeedTitles(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting feed titles: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addTags(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting tags: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+	}
+	if collapse && len(payload) > 0 {
+		err = cfg.addStoryClusterMembers(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting story clusters: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+	}
+
+	response := struct {
+		Posts      []Post  `json:"posts"`
+		NextCursor *string `json:"next_cursor"`
+	}{
+		Posts:      payload,
+		NextCursor: nextCursor,
+	}
+	respondWithJSON(w, 200, response)
 }
 
 // Returns 200 status
@@ -361,33 +968,114 @@ func respondWithJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Write(dat)
 }
 
+// Body of every error response. Code is a machine-readable version of Error
+type errorResponse struct {
+	Error        string `json:"error"`
+	Code         string `json:"code"`
+	MissingScope string `json:"missing_scope,omitempty"`
+}
+
+// Machine-readable error codes for statuses that don't need a more specific one
+var statusErrorCodes = map[int]string{
+	400: "bad_request",
+	401: "unauthorized",
+	403: "forbidden",
+	404: "not_found",
+	409: "conflict",
+	413: "too_large",
+	429: "rate_limited",
+	500: "internal_error",
+	502: "bad_gateway",
+	503: "service_unavailable",
+}
+
 // Helper func that responds to an HTTP request
 func respondWithError(w http.ResponseWriter, code int, msg string) {
-	response := struct {
-		Error string `json:"error"`
-	}{
+	errorCode, ok := statusErrorCodes[code]
+	if !ok {
+		errorCode = "error"
+	}
+	respondWithErrorCode(w, code, errorCode, msg)
+}
+
+// Helper func that responds to an HTTP request with a specific machine-readable error code
+func respondWithErrorCode(w http.ResponseWriter, status int, code string, msg string) {
+	respondWithJSON(w, status, errorResponse{
 		Error: msg,
+		Code:  code,
+	})
+}
+
+// Helper func that converts database.User to User, for better looking JSON responses
+func databaseUserToUser(dbUser database.User) User {
+	user := User{
+		ID:          dbUser.ID,
+		CreatedAt:   dbUser.CreatedAt,
+		UpdatedAt:   dbUser.UpdatedAt,
+		Name:        dbUser.Name,
+		IsAdmin:     dbUser.IsAdmin,
+		Preferences: dbUser.Preferences,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbUser.Email.Valid {
+		user.Email = &dbUser.Email.String
 	}
-	respondWithJSON(w, code, response)
+	if dbUser.DisabledAt.Valid {
+		user.DisabledAt = &dbUser.DisabledAt.Time
+	}
+	return user
 }
 
 // Helper func that converts database.Feed to Feed, for better looking JSON responses
 func databaseFeedToFeed(dbFeed database.Feed) Feed {
 	feed := Feed{
-		ID:        dbFeed.ID,
-		CreatedAt: dbFeed.CreatedAt,
-		UpdatedAt: dbFeed.UpdatedAt,
-		Name:      dbFeed.Name,
-		Url:       dbFeed.Url,
-		UserID:    dbFeed.UserID,
+		ID:            dbFeed.ID,
+		CreatedAt:     dbFeed.CreatedAt,
+		UpdatedAt:     dbFeed.UpdatedAt,
+		Name:          dbFeed.Name,
+		Url:           dbFeed.Url,
+		FetchFullText: dbFeed.FetchFullText,
+		Dormant:       dbFeed.Dormant,
+	}
+	// Feeds whose creator deleted their account & that nobody else follows have no owner
+	if dbFeed.UserID.Valid {
+		feed.UserID = &dbFeed.UserID.UUID
 	}
 	// If dbFeed.LastFetchedAt is NULL, keep the zero value (nil) of feed.LastFetchedAt
 	if dbFeed.LastFetchedAt.Valid {
 		feed.LastFetchedAt = &dbFeed.LastFetchedAt.Time
 	}
+	if dbFeed.RetentionKeep.Valid {
+		feed.RetentionKeep = &dbFeed.RetentionKeep.Int32
+	}
+	if dbFeed.RetentionMaxAgeDays.Valid {
+		feed.RetentionMaxAgeDays = &dbFeed.RetentionMaxAgeDays.Int32
+	}
+	if dbFeed.LastPostAt.Valid {
+		feed.LastPostAt = &dbFeed.LastPostAt.Time
+	}
 	return feed
 }
 
+// Helper func that converts database.FeedFollow to FeedFollow, for better looking JSON responses
+func databaseFeedFollowToFeedFollow(dbFollow database.FeedFollow) FeedFollow {
+	follow := FeedFollow{
+		ID:             dbFollow.ID,
+		CreatedAt:      dbFollow.CreatedAt,
+		UpdatedAt:      dbFollow.UpdatedAt,
+		UserID:         dbFollow.UserID,
+		FeedID:         dbFollow.FeedID,
+		Notes:          dbFollow.Notes,
+		Priority:       dbFollow.Priority,
+		ShowInTimeline: dbFollow.ShowInTimeline,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbFollow.Title.Valid {
+		follow.Title = &dbFollow.Title.String
+	}
+	return follow
+}
+
 // Helper func that converts database.Post to Post, for better looking JSON responses
 func databasePostToPost(dbPost database.Post) Post {
 	post := Post{
@@ -397,30 +1085,61 @@ func databasePostToPost(dbPost database.Post) Post {
 		Title:     dbPost.Title,
 		Url:       dbPost.Url,
 		FeedID:    dbPost.FeedID,
+		ClusterID: dbPost.ClusterID,
 	}
 	// If NULL, keep the zero value (nil)
 	if dbPost.Description.Valid {
 		post.Description = dbPost.Description.String
 	}
+	if dbPost.Content.Valid {
+		post.Content = dbPost.Content.String
+	}
+	if dbPost.Author.Valid {
+		post.Author = dbPost.Author.String
+	}
 	if dbPost.PublishedAt.Valid {
 		post.PublishedAt = dbPost.PublishedAt.Time
 	}
 	return post
 }
 
-// Middleware helper that authenticates a user before handing off the request to the handler
-func (cfg *apiConfig) middlewareAuth(handler authedHandler) http.HandlerFunc {
+// Middleware helper that authenticates a user before handing off the request to the handler.
+// Accepts either a session's access token ("Bearer <token>") or an API key ("ApiKey <key>").
+// API keys must grant every one of the required scopes, sessions have full access
+func (cfg *apiConfig) middlewareAuth(handler authedHandler, scopes ...string) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("Authorization")
-		apiKey = strings.TrimPrefix(apiKey, "ApiKey ")
+		// Checked before authenticating, so requests with bad credentials can't hit the DB without limit
+		if !cfg.allowIP(w, r) {
+			return
+		}
+		creds, authErr := cfg.authenticate(r)
+		if authErr != nil {
+			respondWithAuthError(w, authErr)
+			return
+		}
+		if scope := missingScope(creds.Scopes, scopes); scope != "" {
+			respondWithMissingScope(w, scope)
+			return
+		}
+		bucket, limit := cfg.RateLimiter.limitFor(scopes)
+		if !cfg.RateLimiter.allow(w, r, creds.Key+"|"+bucket, limit) {
+			return
+		}
+		// Handlers that hand out access, like creating API keys, check what the caller was granted
+		r = r.WithContext(context.WithValue(r.Context(), grantedScopesKey{}, creds.Scopes))
+		handler(w, r, creds.User)
+	})
+}
 
-		user, err := cfg.DB.GetUserByAPIKey(r.Context(), apiKey)
-		if err != nil {
-			respondWithError(w, 400, "Unauthorized")
+// Middleware helper that only hands off the request to the handler if the authenticated user is an admin
+func (cfg *apiConfig) middlewareAdmin(handler authedHandler) http.HandlerFunc {
+	return cfg.middlewareAuth(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if !user.IsAdmin {
+			respondWithErrorCode(w, 403, "admin_required", "Admin access required")
 			return
 		}
 		handler(w, r, user)
-	})
+	}, scopeAdmin)
 }
 
 // Background goroutine for updating feeds
@@ -440,7 +1159,14 @@ func (cfg *apiConfig) fetchFeedsWorker() {
 
 		fmt.Printf("Fetched %v with %v posts!\n", rss.Channel.Title, len(rss.Channel.Items))
 
+		// Followers' filter rules are run on each new post
+		filterRules, err := cfg.loadFilterRules(ctx, feed.ID)
+		if err != nil {
+			fmt.Printf("Error loading filter rules for %v: %v\n", feed.Url, err)
+		}
+
 		// Recursively adds each post to the database
+		hasNewPosts := false
 		for _, post := range rss.Channel.Items {
 			// Attempts to parse posts 'description' & 'published date' to sql.NullString & sql.NullTime types respectively
 			var postDescription sql.NullString
@@ -457,23 +1183,79 @@ func (cfg *apiConfig) fetchFeedsWorker() {
 				}
 			}
 
-			// Assembles post data into a struct, then passes it to the database
+			// Assembles post data into a struct, then passes it to the database.
+			// Each post starts out as its own story, until it's clustered below
+			postID := uuid.New()
 			postParams := database.AddPostParams{
-				ID:          uuid.New(),
-				Title:       post.Title,
-				Url:         post.Link,
-				Description: postDescription,
-				PublishedAt: postPubDate,
-				FeedID:      feed.ID,
+				ID:           postID,
+				Title:        post.Title,
+				Url:          post.Link,
+				Description:  postDescription,
+				PublishedAt:  postPubDate,
+				FeedID:       feed.ID,
+				CanonicalUrl: canonicalizeURL(post.Link),
+				ClusterID:    postID,
+				SearchConfig: searchConfigFor(rss.Channel.Language),
+			}
+			// RSS 2.0 <author> is meant to be an email, most feeds use Dublin Core's <dc:creator> for the name
+			author := post.Creator
+			if author == "" {
+				author = post.Author
+			}
+			if author != "" {
+				postParams.Author = sql.NullString{String: author, Valid: true}
+			}
+			fingerprint := storyFingerprint(post.Title, post.Description)
+			if fingerprint != 0 {
+				postParams.Fingerprint = sql.NullInt64{Int64: int64(fingerprint), Valid: true}
+			}
+			// Posts removed by retention would otherwise come back as new posts while they're still in the feed
+			pruned, err := cfg.DB.MarkPrunedPostSeen(ctx, database.MarkPrunedPostSeenParams{FeedID: feed.ID, Url: post.Link})
+			if err != nil {
+				fmt.Printf("Error checking pruned posts for %v: %v\n", post.Link, err)
+			}
+			if pruned > 0 {
+				continue
+			}
+			err = cfg.DB.AddPost(ctx, postParams)
+			// Posts that were already stored fail the insert, so only new posts get clustered & their full text fetched
+			if err != nil {
+				continue
+			}
+			hasNewPosts = true
+
+			// Groups the post with other feeds' posts about the same story
+			clusterID, err := cfg.findStoryCluster(ctx, postID, feed.ID, postParams.CanonicalUrl, fingerprint)
+			if err != nil {
+				fmt.Printf("Error finding story cluster for %v: %v\n", post.Link, err)
+			} else if clusterID != postID {
+				cfg.DB.SetPostCluster(ctx, database.SetPostClusterParams{ID: postID, ClusterID: clusterID})
+			}
+			// Rules run after the full text is fetched, so content rules see the article & not just the feed's summary
+			content := sql.NullString{}
+			if feed.FetchFullText {
+				fullText, err := cfg.fetchFullText(ctx, postID, post.Link)
+				if err != nil {
+					fmt.Printf("Error fetching full text of %v: %v\n", post.Link, err)
+				} else {
+					content = sql.NullString{String: fullText, Valid: true}
+				}
 			}
-			cfg.DB.AddPost(ctx, postParams)
+			cfg.applyFilterRules(ctx, filterRules, postParams, content)
+		}
+		if hasNewPosts {
+			cfg.DB.MarkFeedHasNewPosts(ctx, feed.ID)
 		}
 	}
 	for {
 		// Only lets the loop run once every minute, or the duration set on "ticker"s initialization
 		<-ticker
 
-		feedsToFetch, err := cfg.DB.GetNextFeedsToFetch(ctx, 10)
+		// Feeds nobody follows are skipped, dormant ones are only fetched once per dormantFetchInterval
+		feedsToFetch, err := cfg.DB.GetNextFeedsToFetch(ctx, database.GetNextFeedsToFetchParams{
+			DormantFetchedBefore: time.Now().Add(-dormantFetchInterval),
+			LimitCount:           10,
+		})
 		if err != nil {
 			fmt.Printf("Error fetching feeds: %v", err)
 			continue
@@ -523,6 +1305,20 @@ func fetchRSSFeedData(url string) (Rss, error) {
 	return rssFeed, nil
 }
 
+// Reads an integer env variable, falling back to def when it's unset or invalid
+func envInt(key string, def int) int {
+	valStr := os.Getenv(key)
+	if valStr == "" {
+		return def
+	}
+	val, err := strconv.Atoi(valStr)
+	if err != nil {
+		log.Printf("Invalid value for %v: %v", key, valStr)
+		return def
+	}
+	return val
+}
+
 func parseDate(dateStr string) (time.Time, error) {
 	var t time.Time
 	var err error
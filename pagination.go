@@ -0,0 +1,202 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Page sizes for GET /v1/posts, GET /v1/posts/starred & GET /v1/search
+const (
+	defaultPostsLimit = 20
+	maxPostsLimit     = 100
+)
+
+// Filters shared by the posts & search endpoints. Unset fields don't filter
+type postFilters struct {
+	FeedID   uuid.NullUUID
+	FolderID uuid.NullUUID
+	Since    sql.NullTime
+	Until    sql.NullTime
+	IsRead   sql.NullBool
+	Starred  sql.NullBool
+	Tag      sql.NullString
+	Limit    int32
+}
+
+// Parses ?limit, ?feed_id, ?folder_id, ?since, ?until, ?read, ?starred & ?tag. Responds with 400 & returns false
+// if anything is malformed, instead of quietly ignoring it
+func parsePostFilters(w http.ResponseWriter, r *http.Request) (postFilters, bool) {
+	query := r.URL.Query()
+	filters := postFilters{}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return filters, false
+	}
+	filters.Limit = limit
+
+	if feedStr := query.Get("feed_id"); feedStr != "" {
+		feedID, err := uuid.Parse(feedStr)
+		if err != nil {
+			respondWithError(w, 400, "Invalid FeedID")
+			return filters, false
+		}
+		filters.FeedID = uuid.NullUUID{UUID: feedID, Valid: true}
+	}
+	folderID, err := parseFolderFilter(r)
+	if err != nil {
+		respondWithError(w, 400, "Invalid FolderID")
+		return filters, false
+	}
+	filters.FolderID = folderID
+
+	for _, bound := range []struct {
+		key   string
+		value *sql.NullTime
+	}{{"since", &filters.Since}, {"until", &filters.Until}} {
+		if timeStr := query.Get(bound.key); timeStr != "" {
+			t, err := time.Parse(time.RFC3339, timeStr)
+			if err != nil {
+				respondWithError(w, 400, "Invalid "+bound.key+", expected an RFC 3339 timestamp")
+				return filters, false
+			}
+			*bound.value = sql.NullTime{Time: t, Valid: true}
+		}
+	}
+
+	// ?unread=true is kept from before ?read existed
+	if query.Get("unread") == "true" {
+		filters.IsRead = sql.NullBool{Bool: false, Valid: true}
+	}
+	for _, filter := range []struct {
+		key   string
+		value *sql.NullBool
+	}{{"read", &filters.IsRead}, {"starred", &filters.Starred}} {
+		if boolStr := query.Get(filter.key); boolStr != "" {
+			b, err := strconv.ParseBool(boolStr)
+			if err != nil {
+				respondWithError(w, 400, "Invalid "+filter.key+", expected true or false")
+				return filters, false
+			}
+			*filter.value = sql.NullBool{Bool: b, Valid: true}
+		}
+	}
+	if tag := strings.TrimSpace(query.Get("tag")); tag != "" {
+		filters.Tag = sql.NullString{String: tag, Valid: true}
+	}
+	return filters, true
+}
+
+// Parses ?limit, which defaults to defaultPostsLimit & is capped at maxPostsLimit. Responds with 400 & returns false if it's invalid
+func parseLimit(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return defaultPostsLimit, true
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		respondWithError(w, 400, "Invalid limit")
+		return 0, false
+	}
+	return int32(min(limit, maxPostsLimit)), true
+}
+
+// Parses the GET /v1/posts query into params for GetPostsByUser: the shared filters, plus ?sort, ?cursor, ?collapse
+// & ?include_hidden, which shows posts hidden by filter rules
+func parsePostsQuery(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.GetPostsByUserParams, bool) {
+	query := r.URL.Query()
+	filters, ok := parsePostFilters(w, r)
+	if !ok {
+		return database.GetPostsByUserParams{}, false
+	}
+	params := database.GetPostsByUserParams{
+		UserID:        userID,
+		FolderID:      filters.FolderID,
+		FeedID:        filters.FeedID,
+		Collapse:      query.Get("collapse") == "true",
+		IncludeHidden: query.Get("include_hidden") == "true",
+		Since:         filters.Since,
+		Until:         filters.Until,
+		IsRead:        filters.IsRead,
+		Starred:       filters.Starred,
+		Tag:           filters.Tag,
+		LimitCount:    filters.Limit,
+	}
+
+	switch query.Get("sort") {
+	case "", "newest":
+	case "oldest":
+		params.OldestFirst = true
+	default:
+		respondWithError(w, 400, "sort must be newest or oldest")
+		return params, false
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		sortKey, postID, err := decodeTimeCursor(cursor)
+		if err != nil {
+			respondWithError(w, 400, "Invalid cursor")
+			return params, false
+		}
+		params.CursorTime = sql.NullTime{Time: sortKey, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: postID, Valid: true}
+	}
+	return params, true
+}
+
+// Points at the last post of a page, by the same key the posts are sorted on
+func encodePostCursor(post database.Post) string {
+	sortKey := post.CreatedAt
+	if post.PublishedAt.Valid {
+		sortKey = post.PublishedAt.Time
+	}
+	return encodeTimeCursor(sortKey, post.ID)
+}
+
+// Cursor for lists sorted by a timestamp
+func encodeTimeCursor(sortKey time.Time, id uuid.UUID) string {
+	return encodeCursor(sortKey.UTC().Format(time.RFC3339Nano), id)
+}
+
+func decodeTimeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	key, id, err := decodeCursor(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	sortKey, err := time.Parse(time.RFC3339Nano, key)
+	if err != nil {
+		return time.Time{}, uuid.Nil, err
+	}
+	return sortKey, id, nil
+}
+
+// Cursors are a sort key & the ID of the last item on a page. They're opaque to clients, so the format can change
+func encodeCursor(key string, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(key + "|" + id.String()))
+}
+
+func decodeCursor(cursor string) (string, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	// IDs never contain "|", so splitting on the last one keeps any in the key
+	sep := strings.LastIndex(string(raw), "|")
+	if sep == -1 {
+		return "", uuid.Nil, errors.New("malformed cursor")
+	}
+	key, idStr := string(raw[:sep]), string(raw[sep+1:])
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	return key, id, nil
+}
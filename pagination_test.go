@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.MustParse("5f0c6a3e-8d2b-4c1f-9a7e-2b3c4d5e6f70")
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"timestamp", "2024-03-01T12:30:45.123456789Z"},
+		{"rank", "0.0607927"},
+		{"empty key", ""},
+		{"key with the separator", "a|b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, gotID, err := decodeCursor(encodeCursor(tt.key, id))
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if key != tt.key || gotID != id {
+				t.Errorf("got %q, %v, want %q, %v", key, gotID, tt.key, id)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejectsMalformed(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor!"},
+		{"no separator", encode("2024-03-01T12:30:45Z")},
+		{"invalid id", encode("2024-03-01T12:30:45Z|not-a-uuid")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("expected an error decoding %q", tt.cursor)
+			}
+		})
+	}
+
+	if _, _, err := decodeTimeCursor(encodeCursor("yesterday", uuid.New())); err == nil {
+		t.Error("expected an error for a key that isn't a timestamp")
+	}
+}
+
+func TestTimeCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name    string
+		sortKey time.Time
+	}{
+		{"utc", time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC)},
+		{"nanoseconds", time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)},
+		{"other time zone", time.Date(2024, 3, 1, 12, 30, 45, 0, time.FixedZone("UTC+2", 2*60*60))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortKey, gotID, err := decodeTimeCursor(encodeTimeCursor(tt.sortKey, id))
+			if err != nil {
+				t.Fatalf("decodeTimeCursor: %v", err)
+			}
+			if !sortKey.Equal(tt.sortKey) || gotID != id {
+				t.Errorf("got %v, %v, want %v, %v", sortKey, gotID, tt.sortKey, id)
+			}
+		})
+	}
+}
+
+func TestEncodePostCursorUsesTimelineOrder(t *testing.T) {
+	createdAt := time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+	publishedAt := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		post database.Post
+		want time.Time
+	}{
+		{"published", database.Post{ID: uuid.New(), CreatedAt: createdAt, PublishedAt: sql.NullTime{Time: publishedAt, Valid: true}}, publishedAt},
+		{"no published date", database.Post{ID: uuid.New(), CreatedAt: createdAt}, createdAt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sortKey, id, err := decodeTimeCursor(encodePostCursor(tt.post))
+			if err != nil {
+				t.Fatalf("decodeTimeCursor: %v", err)
+			}
+			if !sortKey.Equal(tt.want) || id != tt.post.ID {
+				t.Errorf("got %v, %v, want %v, %v", sortKey, id, tt.want, tt.post.ID)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   int32
+		wantOK bool
+	}{
+		{"", defaultPostsLimit, true},
+		{"?limit=5", 5, true},
+		{"?limit=1000", maxPostsLimit, true},
+		{"?limit=0", 0, false},
+		{"?limit=-3", 0, false},
+		{"?limit=ten", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			limit, ok := parseLimit(w, httptest.NewRequest("GET", "/v1/posts"+tt.query, nil))
+			if ok != tt.wantOK || (ok && limit != tt.want) {
+				t.Errorf("parseLimit = %v, %v, want %v, %v", limit, ok, tt.want, tt.wantOK)
+			}
+			if !ok && w.Code != 400 {
+				t.Errorf("status = %v, want 400", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Buckets that haven't been touched in this long are full again & get dropped
+const rateLimitBucketIdleTTL = time.Hour
+
+// Token bucket settings: PerMinute tokens are added each minute, up to PerMinute. Zero means unlimited
+type rateLimit struct {
+	PerMinute int
+}
+
+// Tokens added per second
+func (l rateLimit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Where token buckets are kept. take() removes a token from the bucket if one is available
+// & returns what's left in it
+type rateLimitStore interface {
+	take(ctx context.Context, key string, limit rateLimit) (tokens float64, allowed bool, err error)
+}
+
+// Rate limits for authenticated requests, per scope, & for unauthenticated ones, per IP.
+// PerIP caps everything an IP sends to authenticated & public routes, before credentials are even checked
+type rateLimiter struct {
+	store     rateLimitStore
+	Default   rateLimit
+	Scopes    map[string]rateLimit
+	Anonymous rateLimit
+	PerIP     rateLimit
+}
+
+// Reads the limits from env variables, as requests per minute: RATE_LIMIT_DEFAULT, RATE_LIMIT_ANONYMOUS, RATE_LIMIT_IP
+// & one per scope, e.g. RATE_LIMIT_POSTS_READ. RATE_LIMIT_STORE=postgres shares buckets between replicas
+func newRateLimiter(db *database.Queries) *rateLimiter {
+	limiter := &rateLimiter{
+		store:     newMemoryRateLimitStore(),
+		Default:   rateLimit{PerMinute: envInt("RATE_LIMIT_DEFAULT", 120)},
+		Scopes:    map[string]rateLimit{},
+		Anonymous: rateLimit{PerMinute: envInt("RATE_LIMIT_ANONYMOUS", 10)},
+		PerIP:     rateLimit{PerMinute: envInt("RATE_LIMIT_IP", 600)},
+	}
+	for scope := range validScopes {
+		if scope == scopeAll {
+			continue
+		}
+		key := "RATE_LIMIT_" + strings.ToUpper(strings.ReplaceAll(scope, ":", "_"))
+		if os.Getenv(key) != "" {
+			limiter.Scopes[scope] = rateLimit{PerMinute: envInt(key, limiter.Default.PerMinute)}
+		}
+	}
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		limiter.store = &postgresRateLimitStore{DB: db}
+	}
+	return limiter
+}
+
+// The strictest limit among a route's scopes, & the name of the bucket it's counted in
+func (l *rateLimiter) limitFor(scopes []string) (string, rateLimit) {
+	name, limit := "default", l.Default
+	for _, scope := range scopes {
+		scopeLimit, ok := l.Scopes[scope]
+		if ok && (limit.PerMinute == 0 || (scopeLimit.PerMinute != 0 && scopeLimit.PerMinute < limit.PerMinute)) {
+			name, limit = scope, scopeLimit
+		}
+	}
+	return name, limit
+}
+
+// Takes a token for a request, setting the X-RateLimit-* headers. Responds with 429 & returns false if
+// the bucket is empty. If the store is unavailable, requests are let through
+func (l *rateLimiter) allow(w http.ResponseWriter, r *http.Request, key string, limit rateLimit) bool {
+	if limit.PerMinute <= 0 {
+		return true
+	}
+	tokens, allowed, err := l.store.take(r.Context(), key, limit)
+	if err != nil {
+		log.Printf("Error checking rate limit: %s", err)
+		return true
+	}
+
+	// Seconds until the bucket is full again
+	reset := math.Ceil((float64(limit.PerMinute) - tokens) / limit.rate())
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.PerMinute))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(reset)))
+	if !allowed {
+		retryAfter := math.Ceil((1 - tokens) / limit.rate())
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, retryAfter))))
+		respondWithErrorCode(w, 429, "rate_limited", "Rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// Middleware helper that rate limits unauthenticated routes by the client's IP
+func (cfg *apiConfig) middlewareRateLimitIP(handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := fmt.Sprintf("ip:%v|%v", clientIP(r), r.URL.Path)
+		if !cfg.RateLimiter.allow(w, r, key, cfg.RateLimiter.Anonymous) {
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// Middleware helper that rate limits public routes, like the image proxy, by the client IP's overall bucket
+func (cfg *apiConfig) middlewareRateLimitPublic(handler http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.allowIP(w, r) {
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// Takes a token from the client IP's overall bucket. Responds with 429 & returns false if it's empty
+func (cfg *apiConfig) allowIP(w http.ResponseWriter, r *http.Request) bool {
+	return cfg.RateLimiter.allow(w, r, "ip:"+clientIP(r), cfg.RateLimiter.PerIP)
+}
+
+// IP a request came from, without the port
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Keeps buckets in memory, so limits are per process
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		buckets:   map[string]*tokenBucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryRateLimitStore) take(ctx context.Context, key string, limit rateLimit) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > rateLimitBucketIdleTTL {
+		for k, bucket := range s.buckets {
+			if now.Sub(bucket.updatedAt) > rateLimitBucketIdleTTL {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	burst := float64(limit.PerMinute)
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: burst, updatedAt: now}
+		s.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updatedAt).Seconds()*limit.rate())
+	bucket.updatedAt = now
+	if bucket.tokens < 1 {
+		return bucket.tokens, false, nil
+	}
+	bucket.tokens--
+	return bucket.tokens, true, nil
+}
+
+// Keeps buckets in the rate_limit_buckets table, so every replica shares the same limits
+type postgresRateLimitStore struct {
+	DB *database.Queries
+}
+
+func (s *postgresRateLimitStore) take(ctx context.Context, key string, limit rateLimit) (float64, bool, error) {
+	bucket, err := s.DB.TakeRateLimitToken(ctx, database.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(limit.PerMinute),
+		Rate:  limit.rate(),
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return bucket.Tokens, bucket.Allowed, nil
+}
+
+// Background goroutine that removes idle buckets from the DB, once an hour
+func (cfg *apiConfig) rateLimitCleanupWorker() {
+	ctx := context.TODO()
+	ticker := time.Tick(time.Hour)
+	for {
+		<-ticker
+
+		_, err := cfg.DB.DeleteStaleRateLimitBuckets(ctx, time.Now().Add(-rateLimitBucketIdleTTL))
+		if err != nil {
+			fmt.Printf("Error deleting rate limit buckets: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Moves a bucket's last update back, as if d had passed
+func ageBucket(s *memoryRateLimitStore, key string, d time.Duration) {
+	s.buckets[key].updatedAt = s.buckets[key].updatedAt.Add(-d)
+}
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	limit := rateLimit{PerMinute: 3}
+
+	tests := []struct {
+		name string
+		// Runs before each take, after the bucket exists
+		before      func(s *memoryRateLimitStore)
+		takes       int
+		wantAllowed []bool
+	}{
+		{
+			name:        "starts full & runs out",
+			takes:       4,
+			wantAllowed: []bool{true, true, true, false},
+		},
+		{
+			name: "refills at PerMinute a minute",
+			before: func(s *memoryRateLimitStore) {
+				// The bucket is emptied by the first 3 takes, 20s adds back 1 token
+				if s.buckets["k"].tokens < 1 {
+					ageBucket(s, "k", 20*time.Second)
+				}
+			},
+			takes:       5,
+			wantAllowed: []bool{true, true, true, true, true},
+		},
+		{
+			name: "partial refills don't allow a request",
+			before: func(s *memoryRateLimitStore) {
+				if s.buckets["k"].tokens < 1 {
+					ageBucket(s, "k", 10*time.Second)
+				}
+			},
+			takes:       5,
+			wantAllowed: []bool{true, true, true, false, true},
+		},
+		{
+			name: "never refills past PerMinute",
+			before: func(s *memoryRateLimitStore) {
+				ageBucket(s, "k", time.Hour)
+			},
+			takes:       4,
+			wantAllowed: []bool{true, true, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMemoryRateLimitStore()
+			for i := 0; i < tt.takes; i++ {
+				if tt.before != nil && s.buckets["k"] != nil {
+					tt.before(s)
+				}
+				tokens, allowed, err := s.take(context.Background(), "k", limit)
+				if err != nil {
+					t.Fatalf("take: %v", err)
+				}
+				if allowed != tt.wantAllowed[i] {
+					t.Errorf("take %v: allowed = %v, want %v", i+1, allowed, tt.wantAllowed[i])
+				}
+				if tokens < 0 || tokens > float64(limit.PerMinute) {
+					t.Errorf("take %v: %v tokens left, outside 0-%v", i+1, tokens, limit.PerMinute)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStoreKeysAreSeparate(t *testing.T) {
+	s := newMemoryRateLimitStore()
+	limit := rateLimit{PerMinute: 1}
+	if _, allowed, _ := s.take(context.Background(), "a", limit); !allowed {
+		t.Fatal("expected the first request for a to be allowed")
+	}
+	if _, allowed, _ := s.take(context.Background(), "a", limit); allowed {
+		t.Error("expected a's bucket to be empty")
+	}
+	if _, allowed, _ := s.take(context.Background(), "b", limit); !allowed {
+		t.Error("expected b to have its own bucket")
+	}
+}
+
+func TestMemoryRateLimitStoreSweepsIdleBuckets(t *testing.T) {
+	s := newMemoryRateLimitStore()
+	limit := rateLimit{PerMinute: 10}
+	s.take(context.Background(), "idle", limit)
+	s.take(context.Background(), "active", limit)
+
+	ageBucket(s, "idle", 2*rateLimitBucketIdleTTL)
+	s.lastSweep = s.lastSweep.Add(-2 * rateLimitBucketIdleTTL)
+	s.take(context.Background(), "active", limit)
+
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("expected the idle bucket to be swept")
+	}
+	if _, ok := s.buckets["active"]; !ok {
+		t.Error("expected the active bucket to be kept")
+	}
+}
+
+func TestRateLimiterLimitFor(t *testing.T) {
+	limiter := &rateLimiter{
+		Default: rateLimit{PerMinute: 120},
+		Scopes: map[string]rateLimit{
+			scopePostsRead:  {PerMinute: 60},
+			scopePostsWrite: {PerMinute: 30},
+		},
+	}
+	tests := []struct {
+		name      string
+		scopes    []string
+		wantName  string
+		wantLimit int
+	}{
+		{"no scopes", nil, "default", 120},
+		{"scope without its own limit", []string{scopeFollowsRead}, "default", 120},
+		{"scope with a limit", []string{scopePostsRead}, scopePostsRead, 60},
+		{"strictest scope wins", []string{scopePostsRead, scopePostsWrite}, scopePostsWrite, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, limit := limiter.limitFor(tt.scopes)
+			if name != tt.wantName || limit.PerMinute != tt.wantLimit {
+				t.Errorf("limitFor(%v) = %v, %v, want %v, %v", tt.scopes, name, limit.PerMinute, tt.wantName, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestRateLimiterAllowHeaders(t *testing.T) {
+	limiter := &rateLimiter{store: newMemoryRateLimitStore()}
+	limit := rateLimit{PerMinute: 2}
+	r := httptest.NewRequest("GET", "/v1/posts", nil)
+
+	w := httptest.NewRecorder()
+	if !limiter.allow(w, r, "k", limit) {
+		t.Fatal("expected the first request to be allowed")
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 1", got)
+	}
+
+	limiter.allow(httptest.NewRecorder(), r, "k", limit)
+	w = httptest.NewRecorder()
+	if limiter.allow(w, r, "k", limit) {
+		t.Fatal("expected the third request to be limited")
+	}
+	if w.Code != 429 {
+		t.Errorf("status = %v, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected a Retry-After header")
+	}
+
+	// Zero means unlimited
+	if !limiter.allow(httptest.NewRecorder(), r, "k", rateLimit{}) {
+		t.Error("expected a zero limit to allow everything")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Most posts that can be marked in one request
+const maxBulkPosts = 1000
+
+// Marks a single post as read
+func (cfg *apiConfig) handlerPostReadPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.setPostReadState(w, r, user, true)
+}
+
+// Marks a single post as unread
+func (cfg *apiConfig) handlerPostUnreadPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.setPostReadState(w, r, user, false)
+}
+
+// Shared by the single post read & unread endpoints
+func (cfg *apiConfig) setPostReadState(w http.ResponseWriter, r *http.Request, user database.User, read bool) {
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid PostID")
+		return
+	}
+	updated, err := cfg.DB.SetPostsReadState(r.Context(), database.SetPostsReadStateParams{
+		UserID:  user.ID,
+		IsRead:  read,
+		PostIds: []uuid.UUID{postID},
+	})
+	if err != nil {
+		log.Printf("Error updating read state: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	// Posts from feeds the user doesn't follow are treated as missing
+	if updated == 0 {
+		respondWithError(w, 404, "Post not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Marks a list of posts as read
+func (cfg *apiConfig) handlerPostsReadPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.setPostsReadState(w, r, user, true)
+}
+
+// Marks a list of posts as unread
+func (cfg *apiConfig) handlerPostsUnreadPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	cfg.setPostsReadState(w, r, user, false)
+}
+
+// Shared by the bulk read & unread endpoints. Responds with how many posts were updated,
+// posts that don't exist or aren't in a followed feed are skipped
+func (cfg *apiConfig) setPostsReadState(w http.ResponseWriter, r *http.Request, user database.User, read bool) {
+	type parameters struct {
+		PostIDs []uuid.UUID `json:"post_ids"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if len(params.PostIDs) == 0 {
+		respondWithError(w, 400, "post_ids cannot be empty")
+		return
+	}
+	if len(params.PostIDs) > maxBulkPosts {
+		respondWithError(w, 400, "Too many post_ids")
+		return
+	}
+
+	updated, err := cfg.DB.SetPostsReadState(r.Context(), database.SetPostsReadStateParams{
+		UserID:  user.ID,
+		IsRead:  read,
+		PostIds: params.PostIDs,
+	})
+	if err != nil {
+		log.Printf("Error updating read state: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	response := struct {
+		Updated int64 `json:"updated"`
+	}{
+		Updated: updated,
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Marks every post in the timeline, in one feed, folder or saved search, as read. The timeline leaves out feeds with show_in_timeline off. Only posts that arrived up to a bound are marked,
+// either a timestamp ("before") or a post ("up_to_post_id", usually the newest one the client has seen),
+// so posts fetched in the meantime stay unread. Without a bound, posts up to now are marked.
+// Stored as a per-feed watermark instead of a row per post, except for saved searches which only match some of a feed's posts
+func (cfg *apiConfig) handlerPostsMarkAllReadPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		FeedID        *uuid.UUID `json:"feed_id"`
+		FolderID      *uuid.UUID `json:"folder_id"`
+		SavedSearchID *uuid.UUID `json:"saved_search_id"`
+		Before        *time.Time `json:"before"`
+		UpToPostID    *uuid.UUID `json:"up_to_post_id"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if params.Before != nil && params.UpToPostID != nil {
+		respondWithError(w, 400, "Only one of before & up_to_post_id can be set")
+		return
+	}
+	scopes := 0
+	for _, set := range []bool{params.FeedID != nil, params.FolderID != nil, params.SavedSearchID != nil} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		respondWithError(w, 400, "Only one of feed_id, folder_id & saved_search_id can be set")
+		return
+	}
+
+	readBefore := time.Now()
+	if params.Before != nil {
+		readBefore = *params.Before
+	}
+	if params.UpToPostID != nil {
+		post, err := cfg.DB.GetPostByID(r.Context(), *params.UpToPostID)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 404, "Post not found")
+			return
+		}
+		if err != nil {
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		readBefore = post.CreatedAt
+	}
+	if params.SavedSearchID != nil {
+		cfg.markSavedSearchRead(w, r, user, *params.SavedSearchID, readBefore)
+		return
+	}
+	var feedID, folderID uuid.NullUUID
+	if params.FeedID != nil {
+		feedID = uuid.NullUUID{UUID: *params.FeedID, Valid: true}
+	}
+	if params.FolderID != nil {
+		folderID = uuid.NullUUID{UUID: *params.FolderID, Valid: true}
+	}
+
+	tx, err := cfg.DBConn.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.DB.WithTx(tx)
+
+	// Watermarks only move forward, marking up to an older bound doesn't make anything unread
+	updated, err := qtx.SetReadWatermarks(r.Context(), database.SetReadWatermarksParams{
+		ReadBefore: readBefore,
+		UserID:     user.ID,
+		FeedID:     feedID,
+		FolderID:   folderID,
+	})
+	if err != nil {
+		log.Printf("Error setting read watermarks: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if feedID.Valid && updated == 0 {
+		respondWithError(w, 404, "Feed not followed")
+		return
+	}
+	if folderID.Valid && updated == 0 {
+		respondWithError(w, 404, "Folder not found or empty")
+		return
+	}
+	// Posts under the watermark that were marked unread are read now, & read ones no longer need a row
+	err = qtx.ClearPostStatesBefore(r.Context(), database.ClearPostStatesBeforeParams{
+		UserID:     user.ID,
+		ReadBefore: readBefore,
+		FeedID:     feedID,
+		FolderID:   folderID,
+	})
+	if err != nil {
+		log.Printf("Error clearing read state: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing read state: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	response := struct {
+		ReadBefore time.Time `json:"read_before"`
+	}{
+		ReadBefore: readBefore,
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Marks the posts matching a saved search as read, one row per post
+func (cfg *apiConfig) markSavedSearchRead(w http.ResponseWriter, r *http.Request, user database.User, savedSearchID uuid.UUID, readBefore time.Time) {
+	savedSearch, ok := cfg.getSavedSearch(w, r, user.ID, savedSearchID)
+	if !ok {
+		return
+	}
+	_, err := cfg.DB.MarkSearchMatchesRead(r.Context(), database.MarkSearchMatchesReadParams{
+		UserID:     user.ID,
+		Query:      savedSearch.Query,
+		ReadBefore: readBefore,
+		FeedID:     savedSearch.FeedID,
+		FolderID:   savedSearch.FolderID,
+		Tag:        savedSearch.Tag,
+		Since:      savedSearch.Since,
+		Until:      savedSearch.Until,
+		Starred:    savedSearch.Starred,
+	})
+	if err != nil {
+		log.Printf("Error marking saved search read: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	response := struct {
+		ReadBefore time.Time `json:"read_before"`
+	}{
+		ReadBefore: readBefore,
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Number of unread posts in each followed feed & saved search, & in the main timeline. Saved searches overlap
+// with feeds, & feeds with show_in_timeline off aren't in the timeline, so neither is counted in the total
+func (cfg *apiConfig) handlerUnreadCountsGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := cfg.DB.GetUnreadCountsByFeed(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	searchCounts, err := cfg.DB.GetSavedSearchUnreadCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	type feedUnreadCount struct {
+		FeedID uuid.UUID `json:"feed_id"`
+		Unread int64     `json:"unread"`
+	}
+	type savedSearchUnreadCount struct {
+		SavedSearchID uuid.UUID `json:"saved_search_id"`
+		Unread        int64     `json:"unread"`
+	}
+	response := struct {
+		Total         int64                    `json:"total"`
+		Feeds         []feedUnreadCount        `json:"feeds"`
+		SavedSearches []savedSearchUnreadCount `json:"saved_searches"`
+	}{
+		Feeds:         []feedUnreadCount{},
+		SavedSearches: []savedSearchUnreadCount{},
+	}
+	for _, count := range counts {
+		if count.ShowInTimeline {
+			response.Total += count.Unread
+		}
+		response.Feeds = append(response.Feeds, feedUnreadCount{FeedID: count.FeedID, Unread: count.Unread})
+	}
+	for _, count := range searchCounts {
+		response.SavedSearches = append(response.SavedSearches, savedSearchUnreadCount{SavedSearchID: count.SavedSearchID, Unread: count.Unread})
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Fills in Read for each post
+func (cfg *apiConfig) addReadState(ctx context.Context, userID uuid.UUID, posts []Post) error {
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	readIDs, err := cfg.DB.GetReadPostIDs(ctx, database.GetReadPostIDsParams{
+		UserID:  userID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	read := map[uuid.UUID]bool{}
+	for _, id := range readIDs {
+		read[id] = true
+	}
+	for i := range posts {
+		posts[i].Read = read[posts[i].ID]
+	}
+	return nil
+}
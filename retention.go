@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// How long a pruned post's URL is remembered after it was last seen in its feed
+const prunedPostTTL = 7 * 24 * time.Hour
+
+// Limits on how many posts a feed keeps. Zero means unlimited
+type retentionPolicy struct {
+	Keep       int
+	MaxAgeDays int
+}
+
+// Effective retention policy of a feed, its own settings take precedence over the global default
+func (cfg *apiConfig) feedRetentionPolicy(feed database.Feed) retentionPolicy {
+	policy := cfg.Retention
+	if feed.RetentionKeep.Valid {
+		policy.Keep = int(feed.RetentionKeep.Int32)
+	}
+	if feed.RetentionMaxAgeDays.Valid {
+		policy.MaxAgeDays = int(feed.RetentionMaxAgeDays.Int32)
+	}
+	return policy
+}
+
+// Deletes posts outside of every feed's retention policy, except starred & tagged ones. Their URLs are kept in pruned_posts,
+// so they aren't fetched again while they're still in the feed. Returns the number of posts removed
+func (cfg *apiConfig) prunePosts(ctx context.Context) (int64, error) {
+	feeds, err := cfg.DB.GetAllFeeds(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get feeds: %v", err)
+	}
+
+	var total int64
+	for _, feed := range feeds {
+		policy := cfg.feedRetentionPolicy(feed)
+		if policy.MaxAgeDays > 0 {
+			deleted, err := cfg.DB.DeleteFeedPostsOlderThan(ctx, database.DeleteFeedPostsOlderThanParams{
+				FeedID: feed.ID,
+				Cutoff: time.Now().AddDate(0, 0, -policy.MaxAgeDays),
+			})
+			if err != nil {
+				return total, fmt.Errorf("prune %v by age: %v", feed.Url, err)
+			}
+			total += deleted
+		}
+		if policy.Keep > 0 {
+			deleted, err := cfg.DB.DeleteFeedPostsBeyondLimit(ctx, database.DeleteFeedPostsBeyondLimitParams{
+				FeedID: feed.ID,
+				Keep:   int32(policy.Keep),
+			})
+			if err != nil {
+				return total, fmt.Errorf("prune %v by count: %v", feed.Url, err)
+			}
+			total += deleted
+		}
+	}
+
+	// Once an item has left its feed, it can't be fetched again & doesn't need remembering
+	_, err = cfg.DB.DeleteStalePrunedPosts(ctx, time.Now().Add(-prunedPostTTL))
+	if err != nil {
+		return total, fmt.Errorf("delete stale pruned posts: %v", err)
+	}
+	return total, nil
+}
+
+// Background goroutine that prunes old posts once an hour
+func (cfg *apiConfig) retentionWorker() {
+	ctx := context.TODO()
+	ticker := time.Tick(time.Hour)
+	for {
+		<-ticker
+
+		deleted, err := cfg.prunePosts(ctx)
+		if err != nil {
+			fmt.Printf("Error pruning posts: %v\n", err)
+		}
+		fmt.Printf("Pruned %v posts\n", deleted)
+	}
+}
+
+// Runs the retention job immediately & reports how many posts were removed
+func (cfg *apiConfig) handlerRetentionRunPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	deleted, err := cfg.prunePosts(r.Context())
+	if err != nil {
+		log.Printf("Error pruning posts: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	response := struct {
+		Deleted int64 `json:"deleted"`
+	}{
+		Deleted: deleted,
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Sets or clears a feed's retention override. Omitted or null fields fall back to the global default
+func (cfg *apiConfig) handlerFeedRetentionPut(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid FeedID")
+		return
+	}
+	type parameters struct {
+		Keep       *int32 `json:"keep"`
+		MaxAgeDays *int32 `json:"max_age_days"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	if (params.Keep != nil && *params.Keep < 0) || (params.MaxAgeDays != nil && *params.MaxAgeDays < 0) {
+		respondWithError(w, 400, "Retention values cannot be negative")
+		return
+	}
+
+	retentionParams := database.SetFeedRetentionParams{ID: feedID}
+	if params.Keep != nil {
+		retentionParams.RetentionKeep = sql.NullInt32{Int32: *params.Keep, Valid: true}
+	}
+	if params.MaxAgeDays != nil {
+		retentionParams.RetentionMaxAgeDays = sql.NullInt32{Int32: *params.MaxAgeDays, Valid: true}
+	}
+	feed, err := cfg.DB.SetFeedRetention(r.Context(), retentionParams)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Feed not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating feed retention: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 200, databaseFeedToFeed(feed))
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Used in databaseSavedSearchToSavedSearch(). Query uses the same syntax as GET /v1/search,
+// the rest are the GET /v1/posts filters of the same name
+type SavedSearch struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Name      string     `json:"name"`
+	Query     string     `json:"query"`
+	FeedID    *uuid.UUID `json:"feed_id"`
+	FolderID  *uuid.UUID `json:"folder_id"`
+	Since     *time.Time `json:"since"`
+	Until     *time.Time `json:"until"`
+	Starred   *bool      `json:"starred"`
+	Tag       *string    `json:"tag"`
+	Sort      string     `json:"sort"`
+	Unread    int64      `json:"unread"`
+}
+
+// Saves a search as a smart feed. Its posts are read with GET /v1/posts?saved_search_id=
+func (cfg *apiConfig) handlerSavedSearchesPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name     string     `json:"name"`
+		Query    string     `json:"query"`
+		FeedID   *uuid.UUID `json:"feed_id"`
+		FolderID *uuid.UUID `json:"folder_id"`
+		Since    *time.Time `json:"since"`
+		Until    *time.Time `json:"until"`
+		Starred  *bool      `json:"starred"`
+		Tag      *string    `json:"tag"`
+		Sort     string     `json:"sort"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Name == "" || params.Query == "" {
+		respondWithError(w, 400, "Name & query cannot be empty")
+		return
+	}
+	if params.Sort == "" {
+		params.Sort = "newest"
+	}
+	if !validSavedSearchSort(w, params.Sort) {
+		return
+	}
+	if !cfg.savedSearchNameAvailable(w, r, user.ID, params.Name, uuid.Nil) {
+		return
+	}
+
+	createParams := database.CreateSavedSearchParams{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      params.Name,
+		Query:     params.Query,
+		Tag:       nullTag(params.Tag),
+		Sort:      params.Sort,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if params.FeedID != nil {
+		createParams.FeedID = uuid.NullUUID{UUID: *params.FeedID, Valid: true}
+	}
+	if params.FolderID != nil {
+		createParams.FolderID = uuid.NullUUID{UUID: *params.FolderID, Valid: true}
+	}
+	if params.Since != nil {
+		createParams.Since = sql.NullTime{Time: *params.Since, Valid: true}
+	}
+	if params.Until != nil {
+		createParams.Until = sql.NullTime{Time: *params.Until, Valid: true}
+	}
+	if params.Starred != nil {
+		createParams.Starred = sql.NullBool{Bool: *params.Starred, Valid: true}
+	}
+	if !cfg.savedSearchScopeValid(w, r, user.ID, createParams.FeedID, createParams.FolderID) {
+		return
+	}
+	savedSearch, err := cfg.DB.CreateSavedSearch(r.Context(), createParams)
+	if err != nil {
+		log.Printf("Error creating saved search: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	respondWithJSON(w, 201, databaseSavedSearchToSavedSearch(savedSearch, 0))
+}
+
+// Lists the authenticated user's saved searches, with their unread counts
+func (cfg *apiConfig) handlerSavedSearchesGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	savedSearches, err := cfg.DB.GetSavedSearchesByUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	unread, err := cfg.savedSearchUnreadCounts(r, user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	payload := []SavedSearch{}
+	for _, savedSearch := range savedSearches {
+		payload = append(payload, databaseSavedSearchToSavedSearch(savedSearch, unread[savedSearch.ID]))
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Updates a saved search. Fields that aren't sent are kept, the filters can be set to null to remove them
+func (cfg *apiConfig) handlerSavedSearchesPatch(w http.ResponseWriter, r *http.Request, user database.User) {
+	savedSearchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid SavedSearchID")
+		return
+	}
+	type parameters struct {
+		Name     *string         `json:"name"`
+		Query    *string         `json:"query"`
+		FeedID   json.RawMessage `json:"feed_id"`
+		FolderID json.RawMessage `json:"folder_id"`
+		Since    json.RawMessage `json:"since"`
+		Until    json.RawMessage `json:"until"`
+		Starred  json.RawMessage `json:"starred"`
+		Tag      json.RawMessage `json:"tag"`
+		Sort     *string         `json:"sort"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+
+	savedSearch, ok := cfg.getSavedSearch(w, r, user.ID, savedSearchID)
+	if !ok {
+		return
+	}
+
+	updateParams := database.UpdateSavedSearchParams{
+		ID:       savedSearch.ID,
+		UserID:   user.ID,
+		Name:     savedSearch.Name,
+		Query:    savedSearch.Query,
+		FeedID:   savedSearch.FeedID,
+		FolderID: savedSearch.FolderID,
+		Since:    savedSearch.Since,
+		Until:    savedSearch.Until,
+		Starred:  savedSearch.Starred,
+		Tag:      savedSearch.Tag,
+		Sort:     savedSearch.Sort,
+	}
+	if params.Name != nil {
+		if *params.Name == "" {
+			respondWithError(w, 400, "Name cannot be empty")
+			return
+		}
+		if !cfg.savedSearchNameAvailable(w, r, user.ID, *params.Name, savedSearch.ID) {
+			return
+		}
+		updateParams.Name = *params.Name
+	}
+	if params.Query != nil {
+		query := strings.TrimSpace(*params.Query)
+		if query == "" {
+			respondWithError(w, 400, "Query cannot be empty")
+			return
+		}
+		updateParams.Query = query
+	}
+	if params.FeedID != nil {
+		if err := json.Unmarshal(params.FeedID, &updateParams.FeedID); err != nil {
+			respondWithError(w, 400, "Invalid FeedID")
+			return
+		}
+	}
+	if params.FolderID != nil {
+		if err := json.Unmarshal(params.FolderID, &updateParams.FolderID); err != nil {
+			respondWithError(w, 400, "Invalid FolderID")
+			return
+		}
+	}
+	// Only checked when they change, so a saved search scoped to a feed that's since been unfollowed can still be renamed
+	if params.FeedID != nil || params.FolderID != nil {
+		if !cfg.savedSearchScopeValid(w, r, user.ID, updateParams.FeedID, updateParams.FolderID) {
+			return
+		}
+	}
+	for _, bound := range []struct {
+		key   string
+		raw   json.RawMessage
+		value *sql.NullTime
+	}{{"since", params.Since, &updateParams.Since}, {"until", params.Until, &updateParams.Until}} {
+		if bound.raw == nil {
+			continue
+		}
+		var t *time.Time
+		if err := json.Unmarshal(bound.raw, &t); err != nil {
+			respondWithError(w, 400, "Invalid "+bound.key+", expected an RFC 3339 timestamp")
+			return
+		}
+		*bound.value = sql.NullTime{}
+		if t != nil {
+			*bound.value = sql.NullTime{Time: *t, Valid: true}
+		}
+	}
+	if params.Starred != nil {
+		var starred *bool
+		if err := json.Unmarshal(params.Starred, &starred); err != nil {
+			respondWithError(w, 400, "Invalid starred, expected true or false")
+			return
+		}
+		updateParams.Starred = sql.NullBool{}
+		if starred != nil {
+			updateParams.Starred = sql.NullBool{Bool: *starred, Valid: true}
+		}
+	}
+	if params.Tag != nil {
+		var tag *string
+		if err := json.Unmarshal(params.Tag, &tag); err != nil {
+			respondWithError(w, 400, "Invalid tag")
+			return
+		}
+		updateParams.Tag = nullTag(tag)
+	}
+	if params.Sort != nil {
+		if !validSavedSearchSort(w, *params.Sort) {
+			return
+		}
+		updateParams.Sort = *params.Sort
+	}
+
+	savedSearch, err = cfg.DB.UpdateSavedSearch(r.Context(), updateParams)
+	if err != nil {
+		log.Printf("Error updating saved search: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	unread, err := cfg.savedSearchUnreadCounts(r, user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	respondWithJSON(w, 200, databaseSavedSearchToSavedSearch(savedSearch, unread[savedSearch.ID]))
+}
+
+// Deletes a saved search
+func (cfg *apiConfig) handlerSavedSearchesDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	savedSearchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid SavedSearchID")
+		return
+	}
+	deleted, err := cfg.DB.DeleteSavedSearch(r.Context(), database.DeleteSavedSearchParams{
+		ID:     savedSearchID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		log.Printf("Error deleting saved search: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Saved search not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Looks up one of the user's saved searches. Responds with 404 & returns false if it isn't theirs
+func (cfg *apiConfig) getSavedSearch(w http.ResponseWriter, r *http.Request, userID uuid.UUID, savedSearchID uuid.UUID) (database.SavedSearch, bool) {
+	savedSearch, err := cfg.DB.GetSavedSearch(r.Context(), database.GetSavedSearchParams{
+		ID:     savedSearchID,
+		UserID: userID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Saved search not found")
+		return savedSearch, false
+	}
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return savedSearch, false
+	}
+	return savedSearch, true
+}
+
+// Responds with 404 & returns false if the feed isn't one the user follows, or the folder isn't theirs
+func (cfg *apiConfig) savedSearchScopeValid(w http.ResponseWriter, r *http.Request, userID uuid.UUID, feedID, folderID uuid.NullUUID) bool {
+	if feedID.Valid {
+		_, err := cfg.DB.GetFeedFollowByFeedID(r.Context(), database.GetFeedFollowByFeedIDParams{
+			FeedID: feedID.UUID,
+			UserID: userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 404, "Feed not followed")
+			return false
+		}
+		if err != nil {
+			respondWithError(w, 500, "Internal server error")
+			return false
+		}
+	}
+	if folderID.Valid {
+		_, err := cfg.DB.GetFolder(r.Context(), database.GetFolderParams{
+			ID:     folderID.UUID,
+			UserID: userID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 404, "Folder not found")
+			return false
+		}
+		if err != nil {
+			respondWithError(w, 500, "Internal server error")
+			return false
+		}
+	}
+	return true
+}
+
+// Responds with 400 & returns false unless sort is one GET /v1/posts takes
+func validSavedSearchSort(w http.ResponseWriter, sort string) bool {
+	if sort != "newest" && sort != "oldest" {
+		respondWithError(w, 400, "sort must be newest or oldest")
+		return false
+	}
+	return true
+}
+
+// A tag filter, trimmed like ?tag. Blank means no filter
+func nullTag(tag *string) sql.NullString {
+	if tag == nil || strings.TrimSpace(*tag) == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: strings.TrimSpace(*tag), Valid: true}
+}
+
+// Number of unread posts matching each of the user's saved searches, by saved search ID
+func (cfg *apiConfig) savedSearchUnreadCounts(r *http.Request, userID uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts, err := cfg.DB.GetSavedSearchUnreadCounts(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	unread := map[uuid.UUID]int64{}
+	for _, count := range counts {
+		unread[count.SavedSearchID] = count.Unread
+	}
+	return unread, nil
+}
+
+// Responds with 409 & returns false if the user already has another saved search with this name
+func (cfg *apiConfig) savedSearchNameAvailable(w http.ResponseWriter, r *http.Request, userID uuid.UUID, name string, savedSearchID uuid.UUID) bool {
+	existing, err := cfg.DB.GetSavedSearchByName(r.Context(), database.GetSavedSearchByNameParams{
+		UserID: userID,
+		Name:   name,
+	})
+	if err == nil && existing.ID != savedSearchID {
+		respondWithError(w, 409, "A saved search with this name already exists")
+		return false
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 500, "Internal server error")
+		return false
+	}
+	return true
+}
+
+// Helper func that converts database.SavedSearch to SavedSearch
+func databaseSavedSearchToSavedSearch(dbSavedSearch database.SavedSearch, unread int64) SavedSearch {
+	savedSearch := SavedSearch{
+		ID:        dbSavedSearch.ID,
+		CreatedAt: dbSavedSearch.CreatedAt,
+		UpdatedAt: dbSavedSearch.UpdatedAt,
+		Name:      dbSavedSearch.Name,
+		Query:     dbSavedSearch.Query,
+		Sort:      dbSavedSearch.Sort,
+		Unread:    unread,
+	}
+	// If NULL, keep the zero value (nil)
+	if dbSavedSearch.FeedID.Valid {
+		savedSearch.FeedID = &dbSavedSearch.FeedID.UUID
+	}
+	if dbSavedSearch.FolderID.Valid {
+		savedSearch.FolderID = &dbSavedSearch.FolderID.UUID
+	}
+	if dbSavedSearch.Since.Valid {
+		savedSearch.Since = &dbSavedSearch.Since.Time
+	}
+	if dbSavedSearch.Until.Valid {
+		savedSearch.Until = &dbSavedSearch.Until.Time
+	}
+	if dbSavedSearch.Starred.Valid {
+		savedSearch.Starred = &dbSavedSearch.Starred.Bool
+	}
+	if dbSavedSearch.Tag.Valid {
+		savedSearch.Tag = &dbSavedSearch.Tag.String
+	}
+	return savedSearch
+}
+
+// Lists a saved search alongside the user's feed follows. It's virtual, there's no feed, so feed_id is the nil UUID
+// & its posts are read with saved_search_id instead
+func savedSearchToFeedFollow(savedSearch database.SavedSearch) FeedFollow {
+	return FeedFollow{
+		ID:            savedSearch.ID,
+		CreatedAt:     savedSearch.CreatedAt,
+		UpdatedAt:     savedSearch.UpdatedAt,
+		UserID:        savedSearch.UserID,
+		FeedTitle:     savedSearch.Name,
+		Title:         &savedSearch.Name,
+		Virtual:       true,
+		SavedSearchID: &savedSearch.ID,
+	}
+}
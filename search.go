@@ -0,0 +1,179 @@
+package main
+
+import (
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Postgres text search configs for the languages feeds declare in <language>, by ISO 639-1 code.
+// Anything else is indexed with 'simple', which matches words exactly without stemming.
+// posts_search_query (sql/schema) ORs a query across all of these, so it needs a new config added too
+var searchConfigs = map[string]string{
+	"da": "danish",
+	"de": "german",
+	"en": "english",
+	"es": "spanish",
+	"fi": "finnish",
+	"fr": "french",
+	"hu": "hungarian",
+	"it": "italian",
+	"nb": "norwegian",
+	"nl": "dutch",
+	"nn": "norwegian",
+	"no": "norwegian",
+	"pt": "portuguese",
+	"ro": "romanian",
+	"ru": "russian",
+	"sv": "swedish",
+	"tr": "turkish",
+}
+
+// Picks the text search config for a feed's language, e.g. "en-us" -> "english"
+func searchConfigFor(language string) string {
+	code, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(language)), "-")
+	if config, ok := searchConfigs[code]; ok {
+		return config
+	}
+	return "simple"
+}
+
+// Marks ts_headline puts around matches in snippets, see SearchPosts. They're stripped from the text first,
+// so they can't come from a post
+const (
+	snippetStartSel = "\x02"
+	snippetStopSel  = "\x03"
+)
+
+// Turns a snippet from SearchPosts into HTML. Everything in it is escaped, only the match markers become <b>
+func snippetToHTML(snippet string) string {
+	snippet = html.EscapeString(html.UnescapeString(snippet))
+	return strings.NewReplacer(snippetStartSel, "<b>", snippetStopSel, "</b>").Replace(snippet)
+}
+
+// A post matching a search. Snippet is an excerpt with the matched words wrapped in <b>
+type SearchResult struct {
+	Post
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// Searches posts in the user's followed feeds for ?q=, best matches first. q supports
+// "quoted phrases", OR & -excluded words. Takes the same filters as GET /v1/posts
+func (cfg *apiConfig) handlerSearchGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		respondWithError(w, 400, "q cannot be empty")
+		return
+	}
+	filters, ok := parsePostFilters(w, r)
+	if !ok {
+		return
+	}
+	params := database.SearchPostsParams{
+		Query:      q,
+		UserID:     user.ID,
+		FeedID:     filters.FeedID,
+		FolderID:   filters.FolderID,
+		Since:      filters.Since,
+		Until:      filters.Until,
+		IsRead:     filters.IsRead,
+		Starred:    filters.Starred,
+		Tag:        filters.Tag,
+		LimitCount: filters.Limit + 1, // One extra tells whether there's another page
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		key, postID, err := decodeCursor(cursor)
+		if err != nil {
+			respondWithError(w, 400, "Invalid cursor")
+			return
+		}
+		rank, err := strconv.ParseFloat(key, 32)
+		if err != nil {
+			respondWithError(w, 400, "Invalid cursor")
+			return
+		}
+		params.CursorRank.Float64, params.CursorRank.Valid = rank, true
+		params.CursorID = uuid.NullUUID{UUID: postID, Valid: true}
+	}
+
+	rows, err := cfg.DB.SearchPosts(r.Context(), params)
+	if err != nil {
+		log.Printf("Error searching posts: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	var nextCursor *string
+	if len(rows) > int(filters.Limit) {
+		rows = rows[:filters.Limit]
+		last := rows[len(rows)-1]
+		cursor := encodeCursor(strconv.FormatFloat(float64(last.Rank), 'g', -1, 32), last.ID)
+		nextCursor = &cursor
+	}
+
+	posts := make([]Post, 0, len(rows))
+	for _, row := range rows {
+		post := databasePostToPost(database.Post{
+			ID:           row.ID,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+			Title:        row.Title,
+			Url:          row.Url,
+			Description:  row.Description,
+			PublishedAt:  row.PublishedAt,
+			FeedID:       row.FeedID,
+			Content:      row.Content,
+			CanonicalUrl: row.CanonicalUrl,
+			Fingerprint:  row.Fingerprint,
+			ClusterID:    row.ClusterID,
+			Author:       row.Author,
+		})
+		post.Description = cfg.proxyImages(post.Description, post.Url)
+		post.Content = cfg.proxyImages(post.Content, post.Url)
+		posts = append(posts, post)
+	}
+	if len(posts) > 0 {
+		err = cfg.addReadState(r.Context(), user.ID, posts)
+		if err != nil {
+			log.Printf("Error getting read state: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addStarredState(r.Context(), user.ID, posts)
+		if err != nil {
+			log.Printf("Error getting starred state: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addFeedTitles(r.Context(), user.ID, posts)
+		if err != nil {
+			log.Printf("Error getting feed titles: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addTags(r.Context(), user.ID, posts)
+		if err != nil {
+			log.Printf("Error getting tags: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+	}
+
+	results := []SearchResult{}
+	for i, post := range posts {
+		results = append(results, SearchResult{Post: post, Rank: rows[i].Rank, Snippet: snippetToHTML(rows[i].Snippet)})
+	}
+	response := struct {
+		Results    []SearchResult `json:"results"`
+		NextCursor *string        `json:"next_cursor"`
+	}{
+		Results:    results,
+		NextCursor: nextCursor,
+	}
+	respondWithJSON(w, 200, response)
+}
@@ -0,0 +1,30 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (id, user_id, name, key_hash, key_prefix, created_at, expires_at, scopes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetAPIKeysByUser :many
+SELECT * FROM api_keys
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetAPIKeysByPrefix :many
+SELECT * FROM api_keys
+WHERE key_prefix = $1;
+
+-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = LOCALTIMESTAMP
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < LOCALTIMESTAMP - INTERVAL '1 minute');
+
+-- name: CountActiveAPIKeysByUser :one
+SELECT count(*) FROM api_keys
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > LOCALTIMESTAMP);
+
+-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys
+WHERE id = $1 AND user_id = $2;
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- name: CreateExportJob :one
+INSERT INTO export_jobs (id, user_id, created_at, updated_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetExportJob :one
+SELECT * FROM export_jobs
+WHERE id = $1 AND user_id = $2;
+
+-- name: CompleteExportJob :exec
+UPDATE export_jobs
+SET updated_at = LOCALTIMESTAMP, completed_at = LOCALTIMESTAMP, status = 'completed', data = $2
+WHERE id = $1;
+
+-- name: FailExportJob :exec
+UPDATE export_jobs
+SET updated_at = LOCALTIMESTAMP, completed_at = LOCALTIMESTAMP, status = 'failed', error = $2
+WHERE id = $1;
+
+-- name: DeleteExpiredExportJobs :execrows
+DELETE FROM export_jobs
+WHERE created_at < sqlc.arg(before)::timestamp;
\ No newline at end of file
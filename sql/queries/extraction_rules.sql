@@ -0,0 +1,18 @@
+-- name: UpsertExtractionRule :one
+INSERT INTO extraction_rules (id, created_at, updated_at, host, selector)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (host) DO UPDATE
+SET updated_at = EXCLUDED.updated_at, selector = EXCLUDED.selector
+RETURNING *;
+
+-- name: GetExtractionRules :many
+SELECT * FROM extraction_rules
+ORDER BY host;
+
+-- name: GetExtractionRuleByHost :one
+SELECT * FROM extraction_rules
+WHERE host = $1;
+
+-- name: DeleteExtractionRule :exec
+DELETE FROM extraction_rules
+WHERE id = $1;
@@ -9,4 +9,43 @@ WHERE id = $1 AND user_id = $2;
 
 -- name: GetFollowedFeeds :many
 SELECT * FROM feed_follows
-WHERE user_id = $1;
\ No newline at end of file
+WHERE user_id = $1
+ORDER BY priority DESC, created_at;
+
+-- name: GetFollowedFeedsWithDetails :many
+SELECT feed_follows.*, feeds.name AS feed_name, feeds.url AS feed_url
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at;
+
+-- name: CountFollowedFeeds :one
+SELECT count(*) FROM feed_follows
+WHERE user_id = $1;
+
+-- name: GetFollowedFeedsInFolder :many
+SELECT feed_follows.* FROM feed_follows
+JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+WHERE feed_follows.user_id = $1 AND feed_follow_folders.folder_id = $2
+ORDER BY feed_follows.priority DESC, feed_follows.created_at;
+
+-- name: GetFeedFollowByID :one
+SELECT * FROM feed_follows
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetFeedFollowByFeedID :one
+SELECT * FROM feed_follows
+WHERE feed_id = $1 AND user_id = $2;
+
+-- name: UpdateFeedFollow :one
+UPDATE feed_follows
+SET title = sqlc.narg(title)::text, notes = sqlc.arg(notes), priority = sqlc.arg(priority),
+    show_in_timeline = sqlc.arg(show_in_timeline), updated_at = LOCALTIMESTAMP
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id)
+RETURNING *;
+
+-- name: GetFeedTitles :many
+SELECT feed_follows.feed_id, COALESCE(feed_follows.title, feeds.name)::text AS feed_title
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1;
\ No newline at end of file
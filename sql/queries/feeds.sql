@@ -1,6 +1,6 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, fetch_full_text)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetAllFeeds :many
@@ -8,11 +8,80 @@ SELECT * FROM feeds;
 
 -- name: GetNextFeedsToFetch :many
 SELECT * FROM feeds
+WHERE EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND (NOT dormant OR last_fetched_at IS NULL OR last_fetched_at < sqlc.arg(dormant_fetched_before)::timestamp)
 ORDER BY last_fetched_at NULLS FIRST
-LIMIT $1;
+LIMIT sqlc.arg(limit_count);
 
 -- name: MarkFeedFetched :one
 UPDATE feeds
 SET updated_at = LOCALTIMESTAMP, last_fetched_at = LOCALTIMESTAMP
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: SetFeedFetchFullText :one
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, fetch_full_text = $2
+WHERE id = $1
+RETURNING *;
+
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, retention_keep = $2, retention_max_age_days = $3
+WHERE id = $1
+RETURNING *;
+
+
+-- name: MarkFeedHasNewPosts :exec
+UPDATE feeds
+SET last_post_at = LOCALTIMESTAMP, dormant = false
+WHERE id = $1;
+
+-- name: MarkDormantFeeds :execrows
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, dormant = true
+WHERE NOT dormant AND COALESCE(last_post_at, created_at) < sqlc.arg(cutoff)::timestamp;
+
+-- name: GetOrphanedFeeds :many
+SELECT feeds.*, (SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id)::bigint AS post_count
+FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM starred_posts
+    JOIN posts ON posts.id = starred_posts.post_id
+    WHERE posts.feed_id = feeds.id
+)
+ORDER BY created_at;
+
+-- name: DeleteOrphanedFeeds :execrows
+DELETE FROM feeds
+WHERE NOT EXISTS (SELECT 1 FROM feed_follows WHERE feed_follows.feed_id = feeds.id)
+AND NOT EXISTS (
+    SELECT 1 FROM starred_posts
+    JOIN posts ON posts.id = starred_posts.post_id
+    WHERE posts.feed_id = feeds.id
+);
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: ReassignUserFeeds :exec
+UPDATE feeds
+SET updated_at = LOCALTIMESTAMP, user_id = (
+    SELECT feed_follows.user_id FROM feed_follows
+    WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> feeds.user_id
+    ORDER BY feed_follows.created_at
+    LIMIT 1
+)
+WHERE feeds.user_id = $1;
+
+-- name: GetFeedsByCreator :many
+SELECT * FROM feeds
+WHERE user_id = $1
+ORDER BY created_at;
\ No newline at end of file
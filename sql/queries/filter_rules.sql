@@ -0,0 +1,25 @@
+-- name: CreateFilterRule :one
+INSERT INTO filter_rules (id, user_id, field, match_type, pattern, action, tag, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetFilterRulesByUser :many
+SELECT * FROM filter_rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteFilterRule :execrows
+DELETE FROM filter_rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetFilterRulesForFeed :many
+SELECT filter_rules.*, COALESCE(feed_follows.title, feeds.name)::text AS feed_title
+FROM filter_rules
+JOIN feed_follows ON feed_follows.user_id = filter_rules.user_id
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = $1;
+
+-- name: HidePost :exec
+INSERT INTO hidden_posts (rule_id, post_id, user_id, created_at)
+VALUES ($1, $2, $3, LOCALTIMESTAMP)
+ON CONFLICT DO NOTHING;
\ No newline at end of file
@@ -0,0 +1,49 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, user_id, name, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetFoldersByUser :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetFolderByName :one
+SELECT * FROM folders
+WHERE user_id = $1 AND name = $2;
+
+-- name: RenameFolder :one
+UPDATE folders
+SET updated_at = LOCALTIMESTAMP, name = $3
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: AddFeedFollowToFolder :execrows
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT feed_follows.id, folders.id
+FROM feed_follows, folders
+WHERE feed_follows.id = sqlc.arg(feed_follow_id)::uuid AND feed_follows.user_id = sqlc.arg(user_id)::uuid
+AND folders.id = sqlc.arg(folder_id)::uuid AND folders.user_id = sqlc.arg(user_id)::uuid
+ON CONFLICT (feed_follow_id, folder_id) DO UPDATE
+SET folder_id = EXCLUDED.folder_id;
+
+-- name: RemoveFeedFollowFromFolder :execrows
+DELETE FROM feed_follow_folders
+USING folders
+WHERE feed_follow_folders.folder_id = folders.id
+AND feed_follow_folders.feed_follow_id = sqlc.arg(feed_follow_id)::uuid
+AND folders.id = sqlc.arg(folder_id)::uuid AND folders.user_id = sqlc.arg(user_id)::uuid;
+
+-- name: GetFolderAssignmentsByUser :many
+SELECT feed_follow_folders.*, feed_follows.feed_id
+FROM feed_follow_folders
+JOIN feed_follows ON feed_follows.id = feed_follow_folders.feed_follow_id
+WHERE feed_follows.user_id = $1;
+
+-- name: GetFolder :one
+SELECT * FROM folders
+WHERE id = $1 AND user_id = $2;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: CreateInviteCode :one
+INSERT INTO invite_codes (id, code, created_by, max_uses, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetInviteCodes :many
+SELECT * FROM invite_codes
+ORDER BY created_at DESC;
+
+-- name: UseInviteCode :one
+UPDATE invite_codes
+SET uses = uses + 1
+WHERE code = $1 AND uses < max_uses AND (expires_at IS NULL OR expires_at > LOCALTIMESTAMP)
+RETURNING *;
+
+-- name: DeleteInviteCode :execrows
+DELETE FROM invite_codes
+WHERE id = $1;
\ No newline at end of file
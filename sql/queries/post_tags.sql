@@ -0,0 +1,42 @@
+-- name: TagPost :execrows
+INSERT INTO post_tags (user_id, post_id, tag, created_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(tag)::text, LOCALTIMESTAMP
+FROM posts
+WHERE posts.id = sqlc.arg(post_id)::uuid
+AND posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+)
+ON CONFLICT (user_id, post_id, tag) DO UPDATE
+SET created_at = post_tags.created_at;
+
+-- name: UntagPost :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND post_id = $2 AND tag = $3;
+
+-- name: DeleteTag :execrows
+DELETE FROM post_tags
+WHERE user_id = $1 AND tag = $2;
+
+-- name: GetTagCounts :many
+SELECT tag, count(*) AS posts
+FROM post_tags
+WHERE user_id = $1
+GROUP BY tag
+ORDER BY tag;
+
+-- name: GetPostTags :many
+SELECT post_id, tag FROM post_tags
+WHERE user_id = sqlc.arg(user_id) AND post_id = ANY(sqlc.arg(post_ids)::uuid[])
+ORDER BY tag;
+
+-- name: GetPostTagsForExport :many
+SELECT post_tags.*, posts.url AS post_url
+FROM post_tags
+JOIN posts ON posts.id = post_tags.post_id
+WHERE post_tags.user_id = $1
+ORDER BY post_tags.tag, post_tags.created_at;
+
+-- name: CountPostTags :one
+SELECT count(*) FROM post_tags
+WHERE user_id = $1;
\ No newline at end of file
@@ -1,12 +1,130 @@
 -- name: AddPost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, LOCALTIMESTAMP, LOCALTIMESTAMP, $2, $3, $4, $5, $6);
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, canonical_url, fingerprint, cluster_id, search_config, author)
+VALUES ($1, LOCALTIMESTAMP, LOCALTIMESTAMP, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
 
 -- name: GetPostsByUser :many
-SELECT * FROM posts
-WHERE feed_id IN (
-    SELECT feed_id FROM feed_follows
-    WHERE user_id = $1
+WITH matching AS NOT MATERIALIZED (
+    SELECT * FROM posts
+    WHERE feed_id IN (
+        SELECT feed_id FROM feed_follows
+        WHERE user_id = sqlc.arg(user_id)
+        -- Feeds hidden from the main timeline still show up when browsing a folder, a saved search or the feed itself,
+        -- & with all_follows
+        AND (show_in_timeline OR sqlc.narg(folder_id)::uuid IS NOT NULL OR sqlc.narg(feed_id)::uuid IS NOT NULL
+            OR sqlc.narg(query)::text IS NOT NULL OR sqlc.arg(all_follows)::boolean)
+    )
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid)
+    AND (sqlc.narg(folder_id)::uuid IS NULL OR posts.feed_id IN (
+        SELECT feed_follows.feed_id FROM feed_follows
+        JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+        WHERE feed_follows.user_id = sqlc.arg(user_id) AND feed_follow_folders.folder_id = sqlc.narg(folder_id)::uuid
+    ))
+    AND (sqlc.arg(include_hidden)::boolean OR NOT EXISTS (
+        SELECT 1 FROM hidden_posts
+        WHERE hidden_posts.user_id = sqlc.arg(user_id) AND hidden_posts.post_id = posts.id
+    ))
+    AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_tags
+        WHERE post_tags.user_id = sqlc.arg(user_id) AND post_tags.post_id = posts.id AND post_tags.tag = sqlc.narg(tag)::text
+    ))
+    AND (sqlc.narg(query)::text IS NULL OR (posts.search_vector @@ posts_search_query(sqlc.narg(query)::text)
+        AND posts.search_vector @@ websearch_to_tsquery(posts.search_config, sqlc.narg(query)::text)))
+    AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+    AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+    AND (sqlc.narg(is_read)::boolean IS NULL OR COALESCE(
+        (SELECT is_read FROM user_post_state
+        WHERE user_post_state.user_id = sqlc.arg(user_id) AND user_post_state.post_id = posts.id),
+        (SELECT posts.created_at <= read_before FROM read_watermarks
+        WHERE read_watermarks.user_id = sqlc.arg(user_id) AND read_watermarks.feed_id = posts.feed_id),
+        false
+    ) = sqlc.narg(is_read)::boolean)
+    AND (sqlc.narg(starred)::boolean IS NULL OR EXISTS (
+        SELECT 1 FROM starred_posts
+        WHERE starred_posts.user_id = sqlc.arg(user_id) AND starred_posts.post_id = posts.id
+    ) = sqlc.narg(starred)::boolean)
+)
+SELECT * FROM matching
+-- A collapsed story is shown as its earliest post that passes the filters
+WHERE (NOT sqlc.arg(collapse)::boolean OR NOT EXISTS (
+    SELECT 1 FROM matching other
+    WHERE other.cluster_id = matching.cluster_id
+    AND (other.created_at, other.id) < (matching.created_at, matching.id)
+))
+AND (sqlc.narg(cursor_time)::timestamp IS NULL
+    OR (sqlc.arg(oldest_first)::boolean AND (COALESCE(matching.published_at, matching.created_at), matching.id) > (sqlc.narg(cursor_time)::timestamp, sqlc.narg(cursor_id)::uuid))
+    OR (NOT sqlc.arg(oldest_first)::boolean AND (COALESCE(matching.published_at, matching.created_at), matching.id) < (sqlc.narg(cursor_time)::timestamp, sqlc.narg(cursor_id)::uuid))
+)
+ORDER BY
+    CASE WHEN sqlc.arg(oldest_first)::boolean THEN COALESCE(matching.published_at, matching.created_at) END ASC,
+    CASE WHEN sqlc.arg(oldest_first)::boolean THEN matching.id END ASC,
+    COALESCE(matching.published_at, matching.created_at) DESC,
+    matching.id DESC
+LIMIT sqlc.arg(limit_count);
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET updated_at = LOCALTIMESTAMP, content = $2
+WHERE id = $1;
+
+-- name: DeleteFeedPostsOlderThan :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.feed_id = sqlc.arg(feed_id) AND COALESCE(posts.published_at, posts.created_at) < sqlc.arg(cutoff)::timestamp
+    AND NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+    RETURNING posts.feed_id, posts.url
+)
+INSERT INTO pruned_posts (feed_id, url, pruned_at, last_seen_at)
+SELECT deleted.feed_id, deleted.url, LOCALTIMESTAMP, LOCALTIMESTAMP FROM deleted
+ON CONFLICT (feed_id, url) DO UPDATE SET pruned_at = EXCLUDED.pruned_at, last_seen_at = EXCLUDED.last_seen_at;
+
+-- name: DeleteFeedPostsBeyondLimit :execrows
+WITH deleted AS (
+    DELETE FROM posts
+    WHERE posts.feed_id = sqlc.arg(feed_id) AND posts.id NOT IN (
+        SELECT kept.id FROM posts kept
+        WHERE kept.feed_id = sqlc.arg(feed_id)
+        ORDER BY COALESCE(kept.published_at, kept.created_at) DESC, kept.id DESC
+        LIMIT sqlc.arg(keep)::int
+    )
+    AND NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+    AND NOT EXISTS (SELECT 1 FROM post_tags WHERE post_tags.post_id = posts.id)
+    RETURNING posts.feed_id, posts.url
 )
-ORDER BY published_at DESC
-LIMIT $2;
\ No newline at end of file
+INSERT INTO pruned_posts (feed_id, url, pruned_at, last_seen_at)
+SELECT deleted.feed_id, deleted.url, LOCALTIMESTAMP, LOCALTIMESTAMP FROM deleted
+ON CONFLICT (feed_id, url) DO UPDATE SET pruned_at = EXCLUDED.pruned_at, last_seen_at = EXCLUDED.last_seen_at;
+
+-- name: GetStoryClusterByCanonicalURL :one
+SELECT cluster_id FROM posts
+WHERE canonical_url = $1 AND id <> $2
+ORDER BY created_at
+LIMIT 1;
+
+-- name: GetRecentFingerprints :many
+SELECT cluster_id, fingerprint FROM posts
+WHERE created_at > sqlc.arg(since)::timestamp AND id <> sqlc.arg(exclude_id) AND feed_id <> sqlc.arg(feed_id)
+-- Fingerprints within 3 bits of each other share at least one of their four 16-bit bands, see fingerprintBands()
+AND (
+    (fingerprint >> 48) & 65535 = sqlc.arg(band_0)::bigint
+    OR (fingerprint >> 32) & 65535 = sqlc.arg(band_1)::bigint
+    OR (fingerprint >> 16) & 65535 = sqlc.arg(band_2)::bigint
+    OR fingerprint & 65535 = sqlc.arg(band_3)::bigint
+);
+
+-- name: SetPostCluster :exec
+UPDATE posts
+SET cluster_id = $2
+WHERE id = $1;
+
+-- name: GetStoryClusterMembers :many
+SELECT posts.id, posts.cluster_id, posts.feed_id, posts.title, posts.url, COALESCE(feed_follows.title, feeds.name)::text AS feed_name
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = sqlc.arg(user_id)
+WHERE posts.cluster_id = ANY(sqlc.arg(cluster_ids)::uuid[])
+ORDER BY posts.created_at;
+
+-- name: GetPostByID :one
+SELECT * FROM posts
+WHERE id = $1;
\ No newline at end of file
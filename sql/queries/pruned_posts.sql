@@ -0,0 +1,8 @@
+-- name: MarkPrunedPostSeen :execrows
+UPDATE pruned_posts
+SET last_seen_at = LOCALTIMESTAMP
+WHERE feed_id = $1 AND url = $2;
+
+-- name: DeleteStalePrunedPosts :execrows
+DELETE FROM pruned_posts
+WHERE last_seen_at < sqlc.arg(seen_before)::timestamp;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets (key, tokens, allowed, updated_at)
+VALUES (sqlc.arg(key), sqlc.arg(burst)::float8 - 1, true, LOCALTIMESTAMP)
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg(burst)::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM LOCALTIMESTAMP - rate_limit_buckets.updated_at) * sqlc.arg(rate)::float8)
+        - CASE WHEN LEAST(sqlc.arg(burst)::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM LOCALTIMESTAMP - rate_limit_buckets.updated_at) * sqlc.arg(rate)::float8) >= 1 THEN 1 ELSE 0 END,
+    allowed = LEAST(sqlc.arg(burst)::float8, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM LOCALTIMESTAMP - rate_limit_buckets.updated_at) * sqlc.arg(rate)::float8) >= 1,
+    updated_at = LOCALTIMESTAMP
+RETURNING tokens, allowed;
+
+-- name: DeleteStaleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < sqlc.arg(before)::timestamp;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- name: SetReadWatermarks :execrows
+INSERT INTO read_watermarks (user_id, feed_id, read_before, updated_at)
+SELECT feed_follows.user_id, feed_follows.feed_id, sqlc.arg(read_before)::timestamp, LOCALTIMESTAMP
+FROM feed_follows
+WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+-- Marking the whole timeline read leaves out feeds hidden from it
+AND (feed_follows.show_in_timeline OR sqlc.narg(feed_id)::uuid IS NOT NULL OR sqlc.narg(folder_id)::uuid IS NOT NULL)
+AND (sqlc.narg(feed_id)::uuid IS NULL OR feed_follows.feed_id = sqlc.narg(feed_id)::uuid)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR feed_follows.id IN (
+    SELECT feed_follow_id FROM feed_follow_folders
+    WHERE feed_follow_folders.folder_id = sqlc.narg(folder_id)::uuid
+))
+ON CONFLICT (user_id, feed_id) DO UPDATE
+SET read_before = GREATEST(read_watermarks.read_before, EXCLUDED.read_before), updated_at = EXCLUDED.updated_at;
+
+-- name: GetReadWatermarksByUser :many
+SELECT read_watermarks.*, feeds.url AS feed_url
+FROM read_watermarks
+JOIN feeds ON feeds.id = read_watermarks.feed_id
+WHERE read_watermarks.user_id = $1;
\ No newline at end of file
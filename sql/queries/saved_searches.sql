@@ -0,0 +1,63 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, user_id, name, query, feed_id, folder_id, since, until, starred, tag, sort, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING *;
+
+-- name: GetSavedSearchesByUser :many
+SELECT * FROM saved_searches
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetSavedSearchByName :one
+SELECT * FROM saved_searches
+WHERE user_id = $1 AND name = $2;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET name = $3, query = $4, feed_id = $5, folder_id = $6, since = $7, until = $8, starred = $9, tag = $10, sort = $11,
+    updated_at = LOCALTIMESTAMP
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetSavedSearchUnreadCounts :many
+SELECT saved_searches.id AS saved_search_id, count(posts.id) AS unread
+FROM saved_searches
+LEFT JOIN posts ON posts.search_vector @@ posts_search_query(saved_searches.query)
+AND posts.search_vector @@ websearch_to_tsquery(posts.search_config, saved_searches.query)
+AND posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = saved_searches.user_id
+)
+AND (saved_searches.feed_id IS NULL OR posts.feed_id = saved_searches.feed_id)
+AND (saved_searches.folder_id IS NULL OR posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+    WHERE feed_follows.user_id = saved_searches.user_id AND feed_follow_folders.folder_id = saved_searches.folder_id
+))
+AND (saved_searches.tag IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = saved_searches.user_id AND post_tags.post_id = posts.id AND post_tags.tag = saved_searches.tag
+))
+AND (saved_searches.since IS NULL OR COALESCE(posts.published_at, posts.created_at) >= saved_searches.since)
+AND (saved_searches.until IS NULL OR COALESCE(posts.published_at, posts.created_at) < saved_searches.until)
+AND (saved_searches.starred IS NULL OR EXISTS (
+    SELECT 1 FROM starred_posts
+    WHERE starred_posts.user_id = saved_searches.user_id AND starred_posts.post_id = posts.id
+) = saved_searches.starred)
+AND NOT COALESCE(
+    (SELECT is_read FROM user_post_state
+    WHERE user_post_state.user_id = saved_searches.user_id AND user_post_state.post_id = posts.id),
+    (SELECT posts.created_at <= read_before FROM read_watermarks
+    WHERE read_watermarks.user_id = saved_searches.user_id AND read_watermarks.feed_id = posts.feed_id),
+    false
+)
+WHERE saved_searches.user_id = $1
+GROUP BY saved_searches.id;
\ No newline at end of file
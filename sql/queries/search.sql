@@ -0,0 +1,48 @@
+-- name: SearchPosts :many
+SELECT posts.*,
+    ts_rank(posts.search_vector, websearch_to_tsquery(posts.search_config, sqlc.arg(query)::text))::real AS rank,
+    ts_headline(
+        posts.search_config,
+        translate(regexp_replace(COALESCE(posts.content, posts.description, posts.title), '<[^>]*>', ' ', 'g'), chr(2) || chr(3), ''),
+        websearch_to_tsquery(posts.search_config, sqlc.arg(query)::text),
+        'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2, MinWords=10, MaxWords=30'
+    )::text AS snippet
+FROM posts
+WHERE posts.search_vector @@ posts_search_query(sqlc.arg(query)::text)
+AND posts.search_vector @@ websearch_to_tsquery(posts.search_config, sqlc.arg(query)::text)
+AND posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE user_id = sqlc.arg(user_id)
+)
+AND NOT EXISTS (
+    SELECT 1 FROM hidden_posts
+    WHERE hidden_posts.user_id = sqlc.arg(user_id) AND hidden_posts.post_id = posts.id
+)
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+    WHERE feed_follows.user_id = sqlc.arg(user_id) AND feed_follow_folders.folder_id = sqlc.narg(folder_id)::uuid
+))
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = sqlc.arg(user_id) AND post_tags.post_id = posts.id AND post_tags.tag = sqlc.narg(tag)::text
+))
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+AND (sqlc.narg(is_read)::boolean IS NULL OR COALESCE(
+    (SELECT is_read FROM user_post_state
+    WHERE user_post_state.user_id = sqlc.arg(user_id) AND user_post_state.post_id = posts.id),
+    (SELECT posts.created_at <= read_before FROM read_watermarks
+    WHERE read_watermarks.user_id = sqlc.arg(user_id) AND read_watermarks.feed_id = posts.feed_id),
+    false
+) = sqlc.narg(is_read)::boolean)
+AND (sqlc.narg(starred)::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM starred_posts
+    WHERE starred_posts.user_id = sqlc.arg(user_id) AND starred_posts.post_id = posts.id
+) = sqlc.narg(starred)::boolean)
+AND (sqlc.narg(cursor_rank)::real IS NULL OR (
+    ts_rank(posts.search_vector, websearch_to_tsquery(posts.search_config, sqlc.arg(query)::text)), posts.id
+) < (sqlc.narg(cursor_rank)::real, sqlc.narg(cursor_id)::uuid))
+ORDER BY rank DESC, posts.id DESC
+LIMIT sqlc.arg(limit_count);
\ No newline at end of file
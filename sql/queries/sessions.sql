@@ -0,0 +1,31 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, access_token_hash, access_expires_at, refresh_token_hash, refresh_expires_at, created_at, updated_at, impersonated_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetSessionByAccessToken :one
+SELECT * FROM sessions
+WHERE access_token_hash = $1;
+
+-- name: GetSessionByRefreshToken :one
+SELECT * FROM sessions
+WHERE refresh_token_hash = $1;
+
+-- name: RotateSession :one
+UPDATE sessions
+SET updated_at = LOCALTIMESTAMP, access_token_hash = sqlc.arg(access_token_hash), access_expires_at = sqlc.arg(access_expires_at),
+    refresh_token_hash = sqlc.arg(refresh_token_hash), refresh_expires_at = sqlc.arg(refresh_expires_at)
+WHERE id = sqlc.arg(id) AND refresh_token_hash = sqlc.arg(old_refresh_token_hash)
+RETURNING *;
+
+-- name: DeleteSessionByAccessToken :exec
+DELETE FROM sessions
+WHERE access_token_hash = $1;
+
+-- name: DeleteOtherSessions :exec
+DELETE FROM sessions
+WHERE user_id = $1 AND access_token_hash <> $2;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions
+WHERE user_id = $1;
\ No newline at end of file
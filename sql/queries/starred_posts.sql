@@ -0,0 +1,39 @@
+-- name: StarPost :execrows
+INSERT INTO starred_posts (user_id, post_id, created_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, LOCALTIMESTAMP
+FROM posts
+WHERE posts.id = sqlc.arg(post_id)::uuid
+AND posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET created_at = starred_posts.created_at;
+
+-- name: UnstarPost :execrows
+DELETE FROM starred_posts
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetStarredPosts :many
+SELECT posts.*, starred_posts.created_at AS starred_at FROM posts
+JOIN starred_posts ON starred_posts.post_id = posts.id
+WHERE starred_posts.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(cursor_time)::timestamp IS NULL
+    OR (starred_posts.created_at, posts.id) < (sqlc.narg(cursor_time)::timestamp, sqlc.narg(cursor_id)::uuid))
+ORDER BY starred_posts.created_at DESC, posts.id DESC
+LIMIT sqlc.arg(limit_count);
+
+-- name: GetStarredPostIDs :many
+SELECT post_id FROM starred_posts
+WHERE user_id = sqlc.arg(user_id) AND post_id = ANY(sqlc.arg(post_ids)::uuid[]);
+
+-- name: GetStarredPostsForExport :many
+SELECT starred_posts.*, posts.title AS post_title, posts.url AS post_url
+FROM starred_posts
+JOIN posts ON posts.id = starred_posts.post_id
+WHERE starred_posts.user_id = $1
+ORDER BY starred_posts.created_at;
+
+-- name: CountStarredPosts :one
+SELECT count(*) FROM starred_posts
+WHERE user_id = $1;
\ No newline at end of file
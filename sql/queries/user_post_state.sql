@@ -0,0 +1,97 @@
+-- name: SetPostsReadState :execrows
+INSERT INTO user_post_state (user_id, post_id, is_read, updated_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(is_read)::boolean, LOCALTIMESTAMP
+FROM posts
+WHERE posts.id = ANY(sqlc.arg(post_ids)::uuid[])
+AND posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET is_read = EXCLUDED.is_read, updated_at = EXCLUDED.updated_at;
+
+-- name: GetReadPostIDs :many
+SELECT posts.id FROM posts
+LEFT JOIN user_post_state ON user_post_state.post_id = posts.id AND user_post_state.user_id = sqlc.arg(user_id)
+LEFT JOIN read_watermarks ON read_watermarks.feed_id = posts.feed_id AND read_watermarks.user_id = sqlc.arg(user_id)
+WHERE posts.id = ANY(sqlc.arg(post_ids)::uuid[])
+AND COALESCE(user_post_state.is_read, posts.created_at <= read_watermarks.read_before, false);
+
+-- name: GetUnreadCountsByFeed :many
+SELECT feed_follows.feed_id, feed_follows.show_in_timeline, count(posts.id) AS unread
+FROM feed_follows
+LEFT JOIN read_watermarks ON read_watermarks.user_id = feed_follows.user_id AND read_watermarks.feed_id = feed_follows.feed_id
+LEFT JOIN posts ON posts.feed_id = feed_follows.feed_id AND NOT COALESCE(
+    (SELECT is_read FROM user_post_state
+    WHERE user_post_state.user_id = feed_follows.user_id AND user_post_state.post_id = posts.id),
+    posts.created_at <= read_watermarks.read_before,
+    false
+)
+WHERE feed_follows.user_id = $1
+GROUP BY feed_follows.feed_id, feed_follows.show_in_timeline;
+
+-- name: GetUserPostStates :many
+SELECT user_post_state.*, posts.url AS post_url
+FROM user_post_state
+JOIN posts ON posts.id = user_post_state.post_id
+WHERE user_post_state.user_id = $1
+ORDER BY user_post_state.updated_at;
+
+-- name: CountUserPostStates :one
+SELECT count(*) FROM user_post_state
+WHERE user_id = $1;
+
+-- name: ClearPostStatesBefore :exec
+DELETE FROM user_post_state
+USING posts
+WHERE user_post_state.post_id = posts.id
+AND user_post_state.user_id = sqlc.arg(user_id)::uuid
+AND posts.created_at <= sqlc.arg(read_before)::timestamp
+AND (sqlc.narg(feed_id)::uuid IS NOT NULL OR sqlc.narg(folder_id)::uuid IS NOT NULL OR posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid AND feed_follows.show_in_timeline
+))
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid AND feed_follow_folders.folder_id = sqlc.narg(folder_id)::uuid
+));
+
+-- name: MarkSearchMatchesRead :execrows
+INSERT INTO user_post_state (user_id, post_id, is_read, updated_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, true, LOCALTIMESTAMP
+FROM posts
+WHERE posts.search_vector @@ posts_search_query(sqlc.arg(query)::text)
+AND posts.search_vector @@ websearch_to_tsquery(posts.search_config, sqlc.arg(query)::text)
+AND posts.created_at <= sqlc.arg(read_before)::timestamp
+AND posts.feed_id IN (
+    SELECT feed_id FROM feed_follows
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid
+)
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id)::uuid)
+AND (sqlc.narg(folder_id)::uuid IS NULL OR posts.feed_id IN (
+    SELECT feed_follows.feed_id FROM feed_follows
+    JOIN feed_follow_folders ON feed_follow_folders.feed_follow_id = feed_follows.id
+    WHERE feed_follows.user_id = sqlc.arg(user_id)::uuid AND feed_follow_folders.folder_id = sqlc.narg(folder_id)::uuid
+))
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags
+    WHERE post_tags.user_id = sqlc.arg(user_id)::uuid AND post_tags.post_id = posts.id AND post_tags.tag = sqlc.narg(tag)::text
+))
+AND (sqlc.narg(since)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since)::timestamp)
+AND (sqlc.narg(until)::timestamp IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until)::timestamp)
+AND (sqlc.narg(starred)::boolean IS NULL OR EXISTS (
+    SELECT 1 FROM starred_posts
+    WHERE starred_posts.user_id = sqlc.arg(user_id)::uuid AND starred_posts.post_id = posts.id
+) = sqlc.narg(starred)::boolean)
+-- Posts that are already read don't need a row
+AND NOT COALESCE(
+    (SELECT is_read FROM user_post_state
+    WHERE user_post_state.user_id = sqlc.arg(user_id)::uuid AND user_post_state.post_id = posts.id),
+    (SELECT posts.created_at <= read_before FROM read_watermarks
+    WHERE read_watermarks.user_id = sqlc.arg(user_id)::uuid AND read_watermarks.feed_id = posts.feed_id),
+    false
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET is_read = EXCLUDED.is_read, updated_at = EXCLUDED.updated_at;
\ No newline at end of file
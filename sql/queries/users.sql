@@ -1,8 +1,46 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name, api_key)
-VALUES ($1, $2, $3, $4, encode(sha256(random()::text::bytea), 'hex'))
+INSERT INTO users (id, created_at, updated_at, name, email, password_hash)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
--- name: GetUserByAPIKey :one
+-- name: GetUserByID :one
 SELECT * FROM users
-WHERE api_key = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1;
+
+-- name: SetUserPassword :exec
+UPDATE users
+SET updated_at = LOCALTIMESTAMP, password_hash = $2
+WHERE id = $1;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE sqlc.arg(query)::text = ''
+OR name ILIKE '%' || sqlc.arg(query)::text || '%'
+OR email ILIKE '%' || sqlc.arg(query)::text || '%'
+ORDER BY created_at
+LIMIT sqlc.arg(limit_count);
+
+-- name: PromoteAdmins :execrows
+UPDATE users
+SET updated_at = LOCALTIMESTAMP, is_admin = true
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND NOT is_admin;
+
+-- name: SetUserDisabledAt :one
+UPDATE users
+SET updated_at = LOCALTIMESTAMP, disabled_at = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: UpdateUser :one
+UPDATE users
+SET updated_at = LOCALTIMESTAMP, name = $2, email = $3, preferences = $4
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
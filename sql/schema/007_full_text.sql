@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN fetch_full_text BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE posts
+ADD COLUMN content TEXT;
+
+CREATE TABLE extraction_rules(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    host TEXT UNIQUE NOT NULL,
+    selector TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE extraction_rules;
+
+ALTER TABLE posts
+DROP COLUMN content;
+
+ALTER TABLE feeds
+DROP COLUMN fetch_full_text;
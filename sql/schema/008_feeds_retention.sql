@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN retention_keep INTEGER,
+ADD COLUMN retention_max_age_days INTEGER;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN retention_keep,
+DROP COLUMN retention_max_age_days;
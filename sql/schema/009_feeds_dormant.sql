@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN last_post_at TIMESTAMP,
+ADD COLUMN dormant BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE feeds
+SET last_post_at = (SELECT max(created_at) FROM posts WHERE posts.feed_id = feeds.id);
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN last_post_at,
+DROP COLUMN dormant;
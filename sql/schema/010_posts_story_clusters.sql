@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN canonical_url TEXT,
+ADD COLUMN fingerprint BIGINT,
+ADD COLUMN cluster_id UUID;
+
+-- Existing posts each start out as their own story
+UPDATE posts
+SET canonical_url = url, cluster_id = id;
+
+ALTER TABLE posts
+ALTER COLUMN canonical_url SET NOT NULL,
+ALTER COLUMN cluster_id SET NOT NULL;
+
+CREATE INDEX posts_canonical_url_idx ON posts (canonical_url);
+CREATE INDEX posts_cluster_id_idx ON posts (cluster_id);
+CREATE INDEX posts_created_at_idx ON posts (created_at);
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN canonical_url,
+DROP COLUMN fingerprint,
+DROP COLUMN cluster_id;
@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE api_keys(
+    id UUID PRIMARY KEY,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    name TEXT NOT NULL,
+    key VARCHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    expires_at TIMESTAMP
+);
+
+-- Every existing key carries over as the user's "default" key
+INSERT INTO api_keys (id, user_id, name, key, created_at)
+SELECT gen_random_uuid(), id, 'default', api_key, created_at FROM users;
+
+ALTER TABLE users
+DROP COLUMN api_key;
+
+-- +goose Down
+ALTER TABLE users
+ADD COLUMN api_key VARCHAR(64) UNIQUE NOT NULL DEFAULT encode(sha256(random()::text::bytea), 'hex');
+
+UPDATE users
+SET api_key = api_keys.key
+FROM api_keys
+WHERE api_keys.user_id = users.id AND api_keys.name = 'default';
+
+DROP TABLE api_keys;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE api_keys
+ADD COLUMN key_hash VARCHAR(64),
+ADD COLUMN key_prefix VARCHAR(8);
+
+UPDATE api_keys
+SET key_hash = encode(sha256(key::bytea), 'hex'), key_prefix = left(key, 8);
+
+ALTER TABLE api_keys
+ALTER COLUMN key_hash SET NOT NULL,
+ALTER COLUMN key_prefix SET NOT NULL,
+ADD CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash),
+DROP COLUMN key;
+
+-- +goose Down
+-- Raw keys can't be recovered from their hashes, so every key is replaced with a new random one
+ALTER TABLE api_keys
+ADD COLUMN key VARCHAR(64) UNIQUE NOT NULL DEFAULT encode(sha256(random()::text::bytea), 'hex'),
+DROP COLUMN key_hash,
+DROP COLUMN key_prefix;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- Existing keys keep full access
+ALTER TABLE api_keys
+ADD COLUMN scopes TEXT[] NOT NULL DEFAULT '{*}';
+
+-- +goose Down
+ALTER TABLE api_keys
+DROP COLUMN scopes;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email TEXT UNIQUE,
+ADD COLUMN password_hash TEXT;
+
+CREATE TABLE sessions(
+    id UUID PRIMARY KEY,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    access_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    access_expires_at TIMESTAMP NOT NULL,
+    refresh_token_hash VARCHAR(64) UNIQUE NOT NULL,
+    refresh_expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE sessions;
+
+ALTER TABLE users
+DROP COLUMN email,
+DROP COLUMN password_hash;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE export_jobs(
+    id UUID PRIMARY KEY,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    data BYTEA,
+    error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE export_jobs;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE invite_codes(
+    id UUID PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    created_by UUID references users(id) ON DELETE SET NULL,
+    max_uses INT NOT NULL DEFAULT 1,
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE invite_codes;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE user_post_state(
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    post_id UUID references posts(id) ON DELETE CASCADE NOT NULL,
+    is_read BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX user_post_state_post_id_idx ON user_post_state (post_id);
+
+-- +goose Down
+DROP TABLE user_post_state;
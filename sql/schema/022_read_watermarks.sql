@@ -0,0 +1,16 @@
+-- +goose Up
+-- Posts in a feed created at or before read_before count as read, unless user_post_state says otherwise
+CREATE TABLE read_watermarks(
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    feed_id UUID references feeds(id) ON DELETE CASCADE NOT NULL,
+    read_before TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, feed_id)
+);
+
+CREATE INDEX posts_feed_id_created_at_idx ON posts (feed_id, created_at);
+
+-- +goose Down
+DROP INDEX posts_feed_id_created_at_idx;
+
+DROP TABLE read_watermarks;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE starred_posts(
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    post_id UUID references posts(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX starred_posts_post_id_idx ON starred_posts (post_id);
+
+-- +goose Down
+DROP TABLE starred_posts;
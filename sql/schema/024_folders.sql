@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE folders(
+    id UUID PRIMARY KEY,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follow_folders(
+    feed_follow_id UUID references feed_follows(id) ON DELETE CASCADE NOT NULL,
+    folder_id UUID references folders(id) ON DELETE CASCADE NOT NULL,
+    PRIMARY KEY (feed_follow_id, folder_id)
+);
+
+CREATE INDEX feed_follow_folders_folder_id_idx ON feed_follow_folders (folder_id);
+
+-- +goose Down
+DROP TABLE feed_follow_folders;
+
+DROP TABLE folders;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Per-user overrides, the feed's own name is used when title is NULL
+ALTER TABLE feed_follows
+ADD COLUMN title TEXT,
+ADD COLUMN notes TEXT NOT NULL DEFAULT '',
+ADD COLUMN priority INT NOT NULL DEFAULT 0,
+ADD COLUMN show_in_timeline BOOLEAN NOT NULL DEFAULT true;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN title,
+DROP COLUMN notes,
+DROP COLUMN priority,
+DROP COLUMN show_in_timeline;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Matches the sort key GET /v1/posts pages on
+CREATE INDEX posts_sort_key_idx ON posts ((COALESCE(published_at, created_at)), id);
+
+-- +goose Down
+DROP INDEX posts_sort_key_idx;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- search_config is the text search config for the feed's language, 'simple' (no stemming) if it's unknown
+ALTER TABLE posts
+ADD COLUMN search_config REGCONFIG NOT NULL DEFAULT 'simple',
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector(search_config, title), 'A') ||
+    setweight(to_tsvector(search_config, COALESCE(description, '') || ' ' || COALESCE(content, '')), 'B')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+
+ALTER TABLE posts
+DROP COLUMN search_vector,
+DROP COLUMN search_config;
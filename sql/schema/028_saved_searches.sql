@@ -0,0 +1,16 @@
+-- +goose Up
+-- A search query with optional feed & folder filters, read like a feed. Goes away with the feed or folder it's scoped to
+CREATE TABLE saved_searches(
+    id UUID PRIMARY KEY,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL,
+    feed_id UUID references feeds(id) ON DELETE CASCADE,
+    folder_id UUID references folders(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE saved_searches;
@@ -0,0 +1,50 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT;
+
+-- Matched against each new post in a followed feed. tag is only set for the tag action
+CREATE TABLE filter_rules(
+    id UUID PRIMARY KEY,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    field TEXT NOT NULL,
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    action TEXT NOT NULL,
+    tag TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX filter_rules_user_id_idx ON filter_rules (user_id);
+
+-- Posts hidden by a rule, deleting the rule shows them again
+CREATE TABLE hidden_posts(
+    rule_id UUID references filter_rules(id) ON DELETE CASCADE NOT NULL,
+    post_id UUID references posts(id) ON DELETE CASCADE NOT NULL,
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (rule_id, post_id)
+);
+
+CREATE INDEX hidden_posts_user_id_post_id_idx ON hidden_posts (user_id, post_id);
+CREATE INDEX hidden_posts_post_id_idx ON hidden_posts (post_id);
+
+CREATE TABLE post_tags(
+    user_id UUID references users(id) ON DELETE CASCADE NOT NULL,
+    post_id UUID references posts(id) ON DELETE CASCADE NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, post_id, tag)
+);
+
+CREATE INDEX post_tags_post_id_idx ON post_tags (post_id);
+
+-- +goose Down
+DROP TABLE post_tags;
+
+DROP TABLE hidden_posts;
+
+DROP TABLE filter_rules;
+
+ALTER TABLE posts
+DROP COLUMN author;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- For listing & counting a user's posts by tag
+CREATE INDEX post_tags_user_id_tag_idx ON post_tags (user_id, tag);
+
+-- +goose Down
+DROP INDEX post_tags_user_id_tag_idx;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- URLs of posts removed by retention, so items still in the feed aren't fetched again as new posts.
+-- last_seen_at is bumped on every fetch that still has the item, once it stops showing up the row is dropped
+CREATE TABLE pruned_posts(
+    feed_id UUID references feeds(id) ON DELETE CASCADE NOT NULL,
+    url TEXT NOT NULL,
+    pruned_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (feed_id, url)
+);
+
+CREATE INDEX pruned_posts_last_seen_at_idx ON pruned_posts (last_seen_at);
+
+-- +goose Down
+DROP TABLE pruned_posts;
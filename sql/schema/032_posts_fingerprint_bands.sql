@@ -0,0 +1,12 @@
+-- +goose Up
+-- One index per 16-bit band of the fingerprint, so ingest only compares posts sharing a band
+CREATE INDEX posts_fingerprint_band_0_idx ON posts (((fingerprint >> 48) & 65535), created_at);
+CREATE INDEX posts_fingerprint_band_1_idx ON posts (((fingerprint >> 32) & 65535), created_at);
+CREATE INDEX posts_fingerprint_band_2_idx ON posts (((fingerprint >> 16) & 65535), created_at);
+CREATE INDEX posts_fingerprint_band_3_idx ON posts ((fingerprint & 65535), created_at);
+
+-- +goose Down
+DROP INDEX posts_fingerprint_band_0_idx;
+DROP INDEX posts_fingerprint_band_1_idx;
+DROP INDEX posts_fingerprint_band_2_idx;
+DROP INDEX posts_fingerprint_band_3_idx;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Keys are looked up by prefix, then their hashes compared in constant time
+CREATE INDEX api_keys_key_prefix_idx ON api_keys (key_prefix);
+
+-- +goose Down
+DROP INDEX api_keys_key_prefix_idx;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- The query in every text search config posts are indexed with, OR'd together. It doesn't depend on the row,
+-- so unlike websearch_to_tsquery(posts.search_config, ...) it can use posts_search_vector_idx.
+-- Matches a superset, queries recheck with the post's own config. Keep in step with searchConfigs in search.go
+-- +goose StatementBegin
+CREATE FUNCTION posts_search_query(query TEXT) RETURNS TSQUERY
+LANGUAGE SQL STABLE PARALLEL SAFE
+AS $$
+    SELECT websearch_to_tsquery('simple', query)
+        || websearch_to_tsquery('danish', query)
+        || websearch_to_tsquery('dutch', query)
+        || websearch_to_tsquery('english', query)
+        || websearch_to_tsquery('finnish', query)
+        || websearch_to_tsquery('french', query)
+        || websearch_to_tsquery('german', query)
+        || websearch_to_tsquery('hungarian', query)
+        || websearch_to_tsquery('italian', query)
+        || websearch_to_tsquery('norwegian', query)
+        || websearch_to_tsquery('portuguese', query)
+        || websearch_to_tsquery('romanian', query)
+        || websearch_to_tsquery('russian', query)
+        || websearch_to_tsquery('spanish', query)
+        || websearch_to_tsquery('swedish', query)
+        || websearch_to_tsquery('turkish', query)
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION posts_search_query(TEXT);
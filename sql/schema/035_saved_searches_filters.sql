@@ -0,0 +1,16 @@
+-- +goose Up
+-- The rest of the GET /v1/posts filters a saved search keeps. sort is 'newest' or 'oldest'
+ALTER TABLE saved_searches
+ADD COLUMN since TIMESTAMP,
+ADD COLUMN until TIMESTAMP,
+ADD COLUMN starred BOOLEAN,
+ADD COLUMN tag TEXT,
+ADD COLUMN sort TEXT NOT NULL DEFAULT 'newest';
+
+-- +goose Down
+ALTER TABLE saved_searches
+DROP COLUMN since,
+DROP COLUMN until,
+DROP COLUMN starred,
+DROP COLUMN tag,
+DROP COLUMN sort;
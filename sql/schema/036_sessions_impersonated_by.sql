@@ -0,0 +1,8 @@
+-- +goose Up
+-- Set on sessions an admin started as another user, to the admin. These can't be refreshed
+ALTER TABLE sessions
+ADD COLUMN impersonated_by UUID references users(id) ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE sessions
+DROP COLUMN impersonated_by;
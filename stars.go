@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Stars a post from one of the user's followed feeds. Starred posts are never pruned
+func (cfg *apiConfig) handlerPostStarPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid PostID")
+		return
+	}
+	starred, err := cfg.DB.StarPost(r.Context(), database.StarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		log.Printf("Error starring post: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if starred == 0 {
+		respondWithError(w, 404, "Post not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Unstars a post
+func (cfg *apiConfig) handlerPostStarDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid PostID")
+		return
+	}
+	unstarred, err := cfg.DB.UnstarPost(r.Context(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		log.Printf("Error unstarring post: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if unstarred == 0 {
+		respondWithError(w, 404, "Post is not starred")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Retrieves the user's starred posts, most recently starred first. Includes posts from feeds they've since unfollowed.
+// Paged like GET /v1/posts, with ?limit & ?cursor
+func (cfg *apiConfig) handlerStarredPostsGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	params := database.GetStarredPostsParams{
+		UserID:     user.ID,
+		LimitCount: limit + 1, // One extra tells whether there's another page
+	}
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		starredAt, postID, err := decodeTimeCursor(cursor)
+		if err != nil {
+			respondWithError(w, 400, "Invalid cursor")
+			return
+		}
+		params.CursorTime = sql.NullTime{Time: starredAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: postID, Valid: true}
+	}
+
+	rows, err := cfg.DB.GetStarredPosts(r.Context(), params)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	var nextCursor *string
+	if len(rows) > int(limit) {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		cursor := encodeTimeCursor(last.StarredAt, last.ID)
+		nextCursor = &cursor
+	}
+
+	payload := []Post{}
+	for _, row := range rows {
+		post := databasePostToPost(database.Post{
+			ID:           row.ID,
+			CreatedAt:    row.CreatedAt,
+			UpdatedAt:    row.UpdatedAt,
+			Title:        row.Title,
+			Url:          row.Url,
+			Description:  row.Description,
+			PublishedAt:  row.PublishedAt,
+			FeedID:       row.FeedID,
+			Content:      row.Content,
+			CanonicalUrl: row.CanonicalUrl,
+			Fingerprint:  row.Fingerprint,
+			ClusterID:    row.ClusterID,
+			Author:       row.Author,
+		})
+		post.Description = cfg.proxyImages(post.Description, post.Url)
+		post.Content = cfg.proxyImages(post.Content, post.Url)
+		post.Starred = true
+		payload = append(payload, post)
+	}
+	if len(payload) > 0 {
+		err = cfg.addReadState(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting read state: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addFeedTitles(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting feed titles: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+		err = cfg.addTags(r.Context(), user.ID, payload)
+		if err != nil {
+			log.Printf("Error getting tags: %s", err)
+			respondWithError(w, 500, "Internal server error")
+			return
+		}
+	}
+
+	response := struct {
+		Posts      []Post  `json:"posts"`
+		NextCursor *string `json:"next_cursor"`
+	}{
+		Posts:      payload,
+		NextCursor: nextCursor,
+	}
+	respondWithJSON(w, 200, response)
+}
+
+// Fills in Starred for each post
+func (cfg *apiConfig) addStarredState(ctx context.Context, userID uuid.UUID, posts []Post) error {
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	starredIDs, err := cfg.DB.GetStarredPostIDs(ctx, database.GetStarredPostIDsParams{
+		UserID:  userID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	starred := map[uuid.UUID]bool{}
+	for _, id := range starredIDs {
+		starred[id] = true
+	}
+	for i := range posts {
+		posts[i].Starred = starred[posts[i].ID]
+	}
+	return nil
+}
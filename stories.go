@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+	"golang.org/x/net/html"
+)
+
+// How far back ingest looks for similar posts, & how many bits two fingerprints may differ by to count as the same story.
+// Candidates are found by matching one of 4 fingerprint bands, which only finds every match while the distance is below 4
+const (
+	storyClusterWindow     = 72 * time.Hour
+	maxFingerprintDistance = 3
+)
+
+// Query params that only track where a click came from
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"ref":     true,
+	"ref_src": true,
+	"cmpid":   true,
+	"spm":     true,
+}
+
+// Words too common to say anything about which story a post is about
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "to": true, "was": true, "were": true, "will": true,
+	"with": true,
+}
+
+var multipleSlashes = regexp.MustCompile(`/{2,}`)
+
+// Normalizes a post link so the same article matches regardless of scheme, "www.", tracking params or fragments
+func canonicalizeURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(link)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	path := multipleSlashes.ReplaceAllString(u.EscapedPath(), "/")
+	path = strings.TrimSuffix(path, "/")
+
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(key)
+		}
+	}
+
+	canonical := host + path
+	// Encode() sorts the params by key
+	if encoded := query.Encode(); encoded != "" {
+		canonical += "?" + encoded
+	}
+	return canonical
+}
+
+// 64-bit SimHash of a post's title & description. Titles count double, since they vary less between outlets
+func storyFingerprint(title, description string) uint64 {
+	var weights [64]int
+	addWords := func(text string, weight int) {
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, word := range words {
+			if stopWords[word] {
+				continue
+			}
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+			for bit := 0; bit < 64; bit++ {
+				if sum&(1<<bit) != 0 {
+					weights[bit] += weight
+				} else {
+					weights[bit] -= weight
+				}
+			}
+		}
+	}
+	addWords(title, 2)
+	addWords(htmlToText(description), 1)
+
+	var fingerprint uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// A fingerprint's four 16-bit bands, highest first, matching the posts_fingerprint_band_N_idx indexes.
+// Fingerprints that differ in fewer than 4 bits always share at least one band
+func fingerprintBands(fingerprint uint64) [4]int64 {
+	var bands [4]int64
+	for i := range bands {
+		bands[i] = int64(fingerprint >> (48 - 16*i) & 0xffff)
+	}
+	return bands
+}
+
+// Number of bits two fingerprints differ by
+func fingerprintDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Plain text of an HTML fragment
+func htmlToText(fragment string) string {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return sb.String()
+		case html.TextToken:
+			sb.Write(z.Text())
+			sb.WriteByte(' ')
+		}
+	}
+}
+
+// Finds the story cluster a newly stored post belongs to, first by canonical URL then by fingerprint.
+// Fingerprints are only compared with other feeds' posts, so a feed's recurring posts (daily digests...) stay apart.
+// Returns postID when the post is a new story
+func (cfg *apiConfig) findStoryCluster(ctx context.Context, postID uuid.UUID, feedID uuid.UUID, canonicalURL string, fingerprint uint64) (uuid.UUID, error) {
+	clusterID, err := cfg.DB.GetStoryClusterByCanonicalURL(ctx, database.GetStoryClusterByCanonicalURLParams{
+		CanonicalUrl: canonicalURL,
+		ID:           postID,
+	})
+	if err == nil {
+		return clusterID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return postID, err
+	}
+	// Posts without any meaningful words all share the empty fingerprint
+	if fingerprint == 0 {
+		return postID, nil
+	}
+
+	bands := fingerprintBands(fingerprint)
+	recent, err := cfg.DB.GetRecentFingerprints(ctx, database.GetRecentFingerprintsParams{
+		Since:     time.Now().Add(-storyClusterWindow),
+		ExcludeID: postID,
+		FeedID:    feedID,
+		Band0:     bands[0],
+		Band1:     bands[1],
+		Band2:     bands[2],
+		Band3:     bands[3],
+	})
+	if err != nil {
+		return postID, err
+	}
+	bestDistance := maxFingerprintDistance + 1
+	clusterID = postID
+	for _, candidate := range recent {
+		distance := fingerprintDistance(uint64(candidate.Fingerprint.Int64), fingerprint)
+		if distance < bestDistance {
+			bestDistance = distance
+			clusterID = candidate.ClusterID
+		}
+	}
+	return clusterID, nil
+}
+
+// Another feed's post about the same story
+type StoryClusterMember struct {
+	PostID   uuid.UUID `json:"post_id"`
+	FeedID   uuid.UUID `json:"feed_id"`
+	FeedName string    `json:"feed_name"`
+	Title    string    `json:"title"`
+	Url      string    `json:"url"`
+}
+
+// Fills in AlsoReportedBy for each post, from the other posts in its story cluster among the user's followed feeds
+func (cfg *apiConfig) addStoryClusterMembers(ctx context.Context, userID uuid.UUID, posts []Post) error {
+	clusterIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		clusterIDs = append(clusterIDs, post.ClusterID)
+	}
+	members, err := cfg.DB.GetStoryClusterMembers(ctx, database.GetStoryClusterMembersParams{
+		ClusterIds: clusterIDs,
+		UserID:     userID,
+	})
+	if err != nil {
+		return err
+	}
+
+	byCluster := map[uuid.UUID][]StoryClusterMember{}
+	for _, member := range members {
+		byCluster[member.ClusterID] = append(byCluster[member.ClusterID], StoryClusterMember{
+			PostID:   member.ID,
+			FeedID:   member.FeedID,
+			FeedName: member.FeedName,
+			Title:    member.Title,
+			Url:      member.Url,
+		})
+	}
+	for i := range posts {
+		for _, member := range byCluster[posts[i].ClusterID] {
+			if member.PostID != posts[i].ID {
+				posts[i].AlsoReportedBy = append(posts[i].AlsoReportedBy, member)
+			}
+		}
+	}
+	return nil
+}
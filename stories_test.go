@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		link string
+		want string
+	}{
+		{"drops scheme & www", "https://www.example.com/news/story", "example.com/news/story"},
+		{"lowercases the host only", "http://Example.COM/News/Story", "example.com/News/Story"},
+		{"drops default ports", "https://example.com:443/story", "example.com/story"},
+		{"keeps other ports", "http://example.com:8080/story", "example.com:8080/story"},
+		{"drops the trailing slash", "https://example.com/story/", "example.com/story"},
+		{"collapses repeated slashes", "https://example.com//news///story", "example.com/news/story"},
+		{"drops the fragment", "https://example.com/story#comments", "example.com/story"},
+		{"drops tracking params", "https://example.com/story?utm_source=rss&UTM_Medium=feed&fbclid=abc&ref=home", "example.com/story"},
+		{"keeps & sorts other params", "https://example.com/story?page=2&id=7&utm_campaign=x", "example.com/story?id=7&page=2"},
+		{"leaves links without a host alone", "  /relative/story  ", "/relative/story"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalizeURL(tt.link); got != tt.want {
+				t.Errorf("canonicalizeURL(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoryFingerprint(t *testing.T) {
+	base := storyFingerprint(
+		"Central bank raises interest rates to curb inflation",
+		"<p>The central bank raised its benchmark interest rate by half a point on Tuesday, citing persistent inflation.</p>",
+	)
+
+	tests := []struct {
+		name        string
+		title       string
+		description string
+		maxDistance int
+		minDistance int
+	}{
+		{
+			name:        "same text",
+			title:       "Central bank raises interest rates to curb inflation",
+			description: "<p>The central bank raised its benchmark interest rate by half a point on Tuesday, citing persistent inflation.</p>",
+			maxDistance: 0,
+		},
+		{
+			name:        "case, markup & stop words don't matter",
+			title:       "CENTRAL BANK RAISES INTEREST RATES TO CURB INFLATION",
+			description: "<div><b>The</b> central bank raised its benchmark interest rate by half a point on Tuesday, citing persistent inflation</div>",
+			maxDistance: 0,
+		},
+		{
+			name:        "unrelated story",
+			title:       "Local team wins championship after dramatic overtime finish",
+			description: "Fans poured into the streets downtown to celebrate the first title in decades.",
+			minDistance: maxFingerprintDistance + 1,
+			maxDistance: 64,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := fingerprintDistance(base, storyFingerprint(tt.title, tt.description))
+			if distance < tt.minDistance || distance > tt.maxDistance {
+				t.Errorf("distance = %v, want between %v & %v", distance, tt.minDistance, tt.maxDistance)
+			}
+		})
+	}
+
+	if fingerprint := storyFingerprint("The and of", "<p>is it to</p>"); fingerprint != 0 {
+		t.Errorf("expected stop words only to give the empty fingerprint, got %x", fingerprint)
+	}
+}
+
+func TestFingerprintBands(t *testing.T) {
+	bands := fingerprintBands(0x0123456789abcdef)
+	want := [4]int64{0x0123, 0x4567, 0x89ab, 0xcdef}
+	if bands != want {
+		t.Errorf("fingerprintBands = %x, want %x", bands, want)
+	}
+	// The highest bit set must still give a positive band, like (fingerprint >> 48) & 65535 does in Postgres
+	if bands := fingerprintBands(1 << 63); bands[0] != 0x8000 {
+		t.Errorf("expected the top band to be 0x8000, got %x", bands[0])
+	}
+}
+
+func TestFingerprintBandsMatchWithinDistance(t *testing.T) {
+	const fingerprint uint64 = 0xdeadbeefcafef00d
+	sharesBand := func(a, b uint64) bool {
+		bandsA, bandsB := fingerprintBands(a), fingerprintBands(b)
+		for i := range bandsA {
+			if bandsA[i] == bandsB[i] {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		name  string
+		flips []int
+		want  bool
+	}{
+		{"identical", nil, true},
+		{"one bit", []int{5}, true},
+		{"three bits in three bands", []int{3, 20, 40}, true},
+		{"three bits in one band", []int{60, 61, 62}, true},
+		{"one bit in every band", []int{1, 17, 33, 49}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := fingerprint
+			for _, bit := range tt.flips {
+				other ^= 1 << bit
+			}
+			if distance := fingerprintDistance(fingerprint, other); distance != len(tt.flips) {
+				t.Errorf("fingerprintDistance = %v, want %v", distance, len(tt.flips))
+			}
+			if got := sharesBand(fingerprint, other); got != tt.want {
+				t.Errorf("sharesBand = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kylods/kFeed/internal/database"
+)
+
+// Longest tag name, in characters
+const maxTagLength = 64
+
+// Tags a post, creating the tag if the user hasn't used it before
+func (cfg *apiConfig) handlerPostTagsPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid PostID")
+		return
+	}
+	type parameters struct {
+		Tag string `json:"tag"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		respondWithError(w, 500, "Something went wrong")
+		return
+	}
+	tag, ok := validateTag(w, params.Tag)
+	if !ok {
+		return
+	}
+
+	tagged, err := cfg.DB.TagPost(r.Context(), database.TagPostParams{
+		UserID: user.ID,
+		PostID: postID,
+		Tag:    tag,
+	})
+	if err != nil {
+		log.Printf("Error tagging post: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	// Posts from feeds the user doesn't follow are treated as missing
+	if tagged == 0 {
+		respondWithError(w, 404, "Post not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Removes a tag from a post
+func (cfg *apiConfig) handlerPostTagsDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid PostID")
+		return
+	}
+	tag, ok := parseTagParam(w, r)
+	if !ok {
+		return
+	}
+	removed, err := cfg.DB.UntagPost(r.Context(), database.UntagPostParams{
+		UserID: user.ID,
+		PostID: postID,
+		Tag:    tag,
+	})
+	if err != nil {
+		log.Printf("Error untagging post: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, 404, "Post doesn't have this tag")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Lists the user's tags & how many posts have each. Posts with a tag are listed with GET /v1/posts?tag=
+func (cfg *apiConfig) handlerTagsGet(w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := cfg.DB.GetTagCounts(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+
+	type tagCount struct {
+		Tag   string `json:"tag"`
+		Posts int64  `json:"posts"`
+	}
+	payload := []tagCount{}
+	for _, count := range counts {
+		payload = append(payload, tagCount{Tag: count.Tag, Posts: count.Posts})
+	}
+	respondWithJSON(w, 200, payload)
+}
+
+// Removes a tag from every post. Filter rules with the tag action keep using it
+func (cfg *apiConfig) handlerTagsDelete(w http.ResponseWriter, r *http.Request, user database.User) {
+	tag, ok := parseTagParam(w, r)
+	if !ok {
+		return
+	}
+	removed, err := cfg.DB.DeleteTag(r.Context(), database.DeleteTagParams{
+		UserID: user.ID,
+		Tag:    tag,
+	})
+	if err != nil {
+		log.Printf("Error deleting tag: %s", err)
+		respondWithError(w, 500, "Internal server error")
+		return
+	}
+	if removed == 0 {
+		respondWithError(w, 404, "Tag not found")
+		return
+	}
+	respondWithJSON(w, 200, "OK")
+}
+
+// Trims a tag & checks its length. Responds with 400 & returns false if it's empty or too long
+func validateTag(w http.ResponseWriter, tag string) (string, bool) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		respondWithError(w, 400, "Tag cannot be empty")
+		return "", false
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		respondWithError(w, 400, "Tag is too long")
+		return "", false
+	}
+	return tag, true
+}
+
+// Parses the {tag} URL param, which is URL-encoded since tags can contain spaces & slashes
+func parseTagParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tag, err := url.PathUnescape(chi.URLParam(r, "tag"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid tag")
+		return "", false
+	}
+	return validateTag(w, tag)
+}
+
+// Fills in Tags for each post
+func (cfg *apiConfig) addTags(ctx context.Context, userID uuid.UUID, posts []Post) error {
+	postIDs := make([]uuid.UUID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	rows, err := cfg.DB.GetPostTags(ctx, database.GetPostTagsParams{
+		UserID:  userID,
+		PostIds: postIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	tags := map[uuid.UUID][]string{}
+	for _, row := range rows {
+		tags[row.PostID] = append(tags[row.PostID], row.Tag)
+	}
+	for i := range posts {
+		posts[i].Tags = tags[posts[i].ID]
+		if posts[i].Tags == nil {
+			posts[i].Tags = []string{}
+		}
+	}
+	return nil
+}